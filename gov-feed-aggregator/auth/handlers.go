@@ -6,7 +6,7 @@ import (
 	"net/http"
 	"strings"
 	"unicode"
-	// "time"
+	"time"
 	// "fmt"
 
 	"github.com/gin-contrib/sessions"
@@ -126,9 +126,10 @@ func SubmitFeedbackHandler(c *gin.Context) {
 
 	log.Printf("📥 Feedback received: user_id=%d, article_id=%s, action=%v", userID, input.ArticleID, input.Action)
 
-	// ✅ Step 1: Get previous action if exists
+	// ✅ Step 1: Get previous action (and when it was given) if exists
 	var prevAction string
-	err := DB.QueryRow(`SELECT action FROM feedback WHERE user_id = $1 AND article_id = $2`, userID, input.ArticleID).Scan(&prevAction)
+	var prevAt time.Time
+	err := DB.QueryRow(`SELECT action, created_at FROM feedback WHERE user_id = $1 AND article_id = $2`, userID, input.ArticleID).Scan(&prevAction, &prevAt)
 	if err != nil && err != sql.ErrNoRows {
 		log.Printf("❌ Failed to fetch previous feedback: %v", err)
 	}
@@ -142,14 +143,14 @@ func SubmitFeedbackHandler(c *gin.Context) {
 			return
 		}
 
-		// ✅ Step 3: Reverse topic scoring
+		// ✅ Step 3: Reverse topic scoring, by what the reaction is still worth
 		switch prevAction {
 		case "like":
-			go UpdateUserTopicsWithWeight(userID, input.ArticleID, -1)
+			go UpdateUserTopicsWithWeight(userID, input.ArticleID, decayedWeight(-1, prevAt))
 		case "save":
-			go UpdateUserTopicsWithWeight(userID, input.ArticleID, -2)
+			go UpdateUserTopicsWithWeight(userID, input.ArticleID, decayedWeight(-2, prevAt))
 		case "dislike":
-			go UpdateUserTopicsWithWeight(userID, input.ArticleID, +1)
+			go UpdateUserTopicsWithWeight(userID, input.ArticleID, decayedWeight(+1, prevAt))
 		}
 
 		c.JSON(http.StatusOK, gin.H{"message": "Feedback removed"})
@@ -178,7 +179,7 @@ func SubmitFeedbackHandler(c *gin.Context) {
 	}
 
 	// ✅ Step 6: Score adjustment
-	weight := 0
+	weight := 0.0
 	switch *input.Action {
 	case "like":
 		weight = 1
@@ -195,8 +196,8 @@ func SubmitFeedbackHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Feedback recorded"})
 }
 
-func UpdateUserTopicsWithWeight(userID int, articleLink string, weight int) {
-	log.Printf("📌 Updating topic scores with weight=%d for user_id=%d on article: %s", weight, userID, articleLink)
+func UpdateUserTopicsWithWeight(userID int, articleLink string, weight float64) {
+	log.Printf("📌 Updating topic scores with weight=%.2f for user_id=%d on article: %s", weight, userID, articleLink)
 
 	// 1. Get article title
 	var title string
@@ -215,17 +216,12 @@ func UpdateUserTopicsWithWeight(userID int, articleLink string, weight int) {
 
 	// 3. Apply weighted insert/update
 	for _, kw := range keywords {
-		_, err := DB.Exec(`
-			INSERT INTO user_topic_preferences (user_id, topic, score)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (user_id, topic)
-			DO UPDATE SET score = user_topic_preferences.score + $3
-		`, userID, kw, weight)
+		err := bumpTopic(userID, kw, weight)
 
 		if err != nil {
 			log.Printf("❌ Failed to update topic score for %q: %v", kw, err)
 		} else {
-			log.Printf("✅ Updated topic %q by %+d for user %d", kw, weight, userID)
+			log.Printf("✅ Updated topic %q by %+.2f for user %d", kw, weight, userID)
 		}
 	}
 }
@@ -288,35 +284,13 @@ func GetUserTopics(c *gin.Context) {
 
 	log.Printf("📦 Fetching topics for user_id: %d", userID)
 
-	rows, err := DB.Query(`
-		SELECT topic, score
-		FROM user_topic_preferences
-		WHERE user_id = $1
-		ORDER BY score DESC
-		LIMIT 10
-	`, userID)
+	// scores are decayed to now, so recent interests come first
+	results, err := TopicScores(c.Request.Context(), userID, 10)
 	if err != nil {
 		log.Println("❌ DB query failed:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
 		return
 	}
-	defer rows.Close()
-
-	results := []struct {
-		Topic string `json:"topic"`
-		Score int    `json:"score"`
-	}{}
-
-	for rows.Next() {
-		var topic string
-		var score int
-		if err := rows.Scan(&topic, &score); err == nil {
-			results = append(results, struct {
-				Topic string `json:"topic"`
-				Score int    `json:"score"`
-			}{Topic: topic, Score: score})
-		}
-	}
 
 	c.JSON(http.StatusOK, results)
 }
@@ -325,30 +299,21 @@ func GetUserTopics(c *gin.Context) {
 // Inside auth/handlers.go
 func GetTopTopicsHandler(c *gin.Context) {
 	session := sessions.Default(c)
-	userID := session.Get("user_id")
-	if userID == nil {
+	userID, ok := session.Get("user_id").(int)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
 		return
 	}
 
-	rows, err := DB.Query(`
-		SELECT topic FROM user_topic_preferences 
-		WHERE user_id = $1 
-		ORDER BY score DESC 
-		LIMIT 5
-	`, userID)
+	scores, err := TopicScores(c.Request.Context(), userID, 5)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get topics"})
 		return
 	}
-	defer rows.Close()
 
 	var topics []string
-	for rows.Next() {
-		var topic string
-		if err := rows.Scan(&topic); err == nil {
-			topics = append(topics, topic)
-		}
+	for _, t := range scores {
+		topics = append(topics, t.Topic)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"topics": topics})
@@ -368,17 +333,18 @@ func UpdateUserTopics(userID int, articleID string) {
 	keywords := extractKeywords(title)
 
 	for _, keyword := range keywords {
-		_, err := DB.Exec(`
-			INSERT INTO user_topic_preferences (user_id, topic, score)
-			VALUES ($1, $2, 1)
-			ON CONFLICT (user_id, topic) DO UPDATE SET score = user_topic_preferences.score + 1
-		`, userID, keyword)
+		err := bumpTopic(userID, keyword, 1)
 		if err != nil {
 			log.Printf("❌ Failed to update topic score for '%s': %v", keyword, err)
 		}
 	}
 }
 
+// ExtractKeywords exposes the topic keyword extractor to the feeds package.
+func ExtractKeywords(text string) []string {
+	return extractKeywords(text)
+}
+
 func extractKeywords(title string) []string {
 	title = strings.ToLower(title)
 	title = strings.TrimPrefix(title, "'")
@@ -431,13 +397,10 @@ func OnboardingHandler(c *gin.Context) {
 	}
 
 	for _, topic := range payload.Topics {
-		DB.Exec(`
-		  INSERT INTO user_topic_preferences (user_id, topic, score)
-		  VALUES ($1, $2, 2)
-		  ON CONFLICT (user_id, topic)
-		  DO UPDATE SET score = user_topic_preferences.score + 2
-		`, userID, strings.ToLower(topic))
-	  }
+		if err := bumpTopic(userID, strings.ToLower(topic), 2); err != nil {
+			log.Printf("❌ Failed to save onboarding topic %q: %v", topic, err)
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Preferences saved"})
 }
@@ -2,6 +2,9 @@ package auth
 
 import (
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -20,3 +23,25 @@ func RequireLogin() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireAdmin only lets through users whose id is listed in the
+// comma-separated ADMIN_USER_IDS env var.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		userID, ok := session.Get("user_id").(int)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "You must be logged in"})
+			return
+		}
+
+		for _, raw := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+			if id, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && id == userID {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+	}
+}
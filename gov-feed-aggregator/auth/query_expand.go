@@ -2,15 +2,20 @@ package auth
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
-	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
 type ExpandQueryRequest struct {
@@ -19,6 +24,25 @@ type ExpandQueryRequest struct {
 
 type ExpandResponse struct {
 	Keywords string `json:"keywords"` // Comma-separated keywords
+	Source   string `json:"source"`   // openai | cache | local
+}
+
+const (
+	expansionCacheTTL = 30 * 24 * time.Hour
+	openAITimeout     = 10 * time.Second
+)
+
+// InitExpansionCache creates the table that remembers expansions per
+// normalized query, so each distinct query costs at most one OpenAI call.
+func InitExpansionCache() error {
+	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS query_expansions (
+			query      TEXT PRIMARY KEY,
+			keywords   TEXT[] NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	return err
 }
 
 func QueryExpansionHandler(c *gin.Context) {
@@ -30,23 +54,89 @@ func QueryExpansionHandler(c *gin.Context) {
 	}
 
 	var req ExpandQueryRequest
-	if err := c.ShouldBindJSON(&req); err != nil || req.Query == "" {
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Query) == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
 		return
 	}
 
+	keywords, source := ExpandQuery(c.Request.Context(), req.Query)
+	c.JSON(http.StatusOK, ExpandResponse{Keywords: strings.Join(keywords, ", "), Source: source})
+}
+
+// ExpandQuery returns 3-5 search keywords for a query and where they came
+// from. Single words come back as-is; longer queries hit the cache, then
+// OpenAI, and fall back to the local extractor when there is no API key or
+// the call fails. It never returns an empty list.
+func ExpandQuery(ctx context.Context, query string) (keywords []string, source string) {
+	norm := normalizeQuery(query)
+
 	// ✂️ Check if it's one word
-	wordCount := len(strings.Fields(req.Query))
-	if wordCount <= 1 {
-		log.Printf("🧃 Skipped OpenAI: simple query detected [%s]", req.Query)
-		c.JSON(http.StatusOK, ExpandResponse{Keywords: req.Query})
-		return
+	if len(strings.Fields(norm)) <= 1 {
+		return []string{norm}, "local"
+	}
+
+	var cached []string
+	err := DB.QueryRowContext(ctx, `
+		SELECT keywords FROM query_expansions
+		WHERE query = $1 AND created_at > $2
+	`, norm, time.Now().Add(-expansionCacheTTL)).Scan(pq.Array(&cached))
+	if err == nil && len(cached) > 0 {
+		return cached, "cache"
+	}
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("⚠️ Expansion cache lookup failed for [%s]: %v", norm, err)
+	}
+
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return localKeywords(norm), "local"
+	}
+
+	log.Printf("🔮 Using OpenAI to expand query: [%s]", norm)
+	keywords, err = openAIKeywords(ctx, apiKey, norm)
+	if err != nil {
+		log.Printf("❌ OpenAI expansion failed for [%s], using local keywords: %v", norm, err)
+		return localKeywords(norm), "local"
+	}
+	log.Printf("✅ OpenAI keywords extracted: %v", keywords)
+
+	_, err = DB.ExecContext(ctx, `
+		INSERT INTO query_expansions (query, keywords) VALUES ($1, $2)
+		ON CONFLICT (query) DO UPDATE SET keywords = EXCLUDED.keywords, created_at = NOW()
+	`, norm, pq.Array(keywords))
+	if err != nil {
+		log.Printf("⚠️ Could not cache expansion for [%s]: %v", norm, err)
 	}
+	return keywords, "openai"
+}
+
+// ExpandQueryLocal is ExpandQuery without the cache or OpenAI, for callers
+// that aren't allowed to spend API calls (anonymous searches).
+func ExpandQueryLocal(query string) []string {
+	norm := normalizeQuery(query)
+	if len(strings.Fields(norm)) <= 1 {
+		return []string{norm}
+	}
+	return localKeywords(norm)
+}
 
-	log.Printf("🔮 Using OpenAI to expand query: [%s]", req.Query)
+// normalizeQuery lower-cases and collapses whitespace so "Drone  Swarms"
+// and "drone swarms" share a cache entry.
+func normalizeQuery(q string) string {
+	return strings.Join(strings.Fields(strings.ToLower(q)), " ")
+}
 
-	// 🧠 Proceed with OpenAI
-	prompt := "Extract 3-5 relevant keywords (comma-separated) from this query for search filtering:\n\n\"" + req.Query + "\""
+// localKeywords is the offline fallback: the same extractor used for topic
+// scores, or the query itself if that finds nothing.
+func localKeywords(norm string) []string {
+	if kws := extractKeywords(norm); len(kws) > 0 {
+		return kws
+	}
+	return []string{norm}
+}
+
+func openAIKeywords(ctx context.Context, apiKey, query string) ([]string, error) {
+	prompt := "Extract 3-5 relevant keywords (comma-separated) from this query for search filtering:\n\n\"" + query + "\""
 
 	openAIReq := map[string]interface{}{
 		"model": "gpt-3.5-turbo",
@@ -57,21 +147,30 @@ func QueryExpansionHandler(c *gin.Context) {
 	}
 
 	buf := new(bytes.Buffer)
-	json.NewEncoder(buf).Encode(openAIReq)
+	if err := json.NewEncoder(buf).Encode(openAIReq); err != nil {
+		return nil, err
+	}
 
-	request, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", buf)
-	request.Header.Set("Authorization", "Bearer "+os.Getenv("OPENAI_API_KEY"))
+	ctx, cancel := context.WithTimeout(ctx, openAITimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", buf)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+apiKey)
 	request.Header.Set("Content-Type", "application/json")
 
 	resp, err := http.DefaultClient.Do(request)
 	if err != nil {
-		log.Printf("❌ OpenAI request failed for query: [%s]", req.Query)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "OpenAI request failed"})
-		return
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openai http %d: %.200s", resp.StatusCode, body)
+	}
 
 	var parsed struct {
 		Choices []struct {
@@ -80,17 +179,23 @@ func QueryExpansionHandler(c *gin.Context) {
 			} `json:"message"`
 		} `json:"choices"`
 	}
-
 	if err := json.Unmarshal(body, &parsed); err != nil || len(parsed.Choices) == 0 {
-		log.Printf("❌ OpenAI response parsing failed for query: [%s]", req.Query)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse OpenAI response"})
-		return
+		return nil, fmt.Errorf("unparseable response")
 	}
 
-	keywords := parsed.Choices[0].Message.Content
-	log.Printf("✅ OpenAI keywords extracted: %s", keywords)
-
-	c.JSON(http.StatusOK, ExpandResponse{Keywords: keywords})
+	var keywords []string
+	seen := map[string]bool{}
+	for _, kw := range strings.Split(parsed.Choices[0].Message.Content, ",") {
+		kw = strings.ToLower(strings.Trim(strings.TrimSpace(kw), `"'.`))
+		if kw != "" && !seen[kw] {
+			seen[kw] = true
+			keywords = append(keywords, kw)
+		}
+	}
+	if len(keywords) == 0 {
+		return nil, fmt.Errorf("no keywords in response")
+	}
+	return keywords, nil
 }
 
 // ✅ Helper
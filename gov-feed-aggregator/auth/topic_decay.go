@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Topic scores fade with a half-life instead of growing forever, so this
+// week's likes outweigh one from two years ago. A row keeps its score as of
+// updated_at; every write first decays it to now, then adds the new weight,
+// and reads decay it to now (decayedScore).
+
+var topicHalfLife = 30 * 24 * time.Hour
+
+// decayedScore is the SQL for a row's score as of NOW(); $1 is the half-life
+// in seconds.
+const decayedScore = `score * POWER(0.5, EXTRACT(EPOCH FROM NOW() - updated_at)::float8 / $1)`
+
+// TopicScore is one of a user's topics with its current (decayed) score.
+type TopicScore struct {
+	Topic string  `json:"topic"`
+	Score float64 `json:"score"`
+}
+
+// InitTopicDecay reads TOPIC_HALF_LIFE (e.g. "720h") and migrates
+// user_topic_preferences to decaying scores: score becomes a float, and
+// updated_at records when it was last current. Rows from before decay get
+// a one-time timestamp (see migrateLegacyTopics).
+func InitTopicDecay() error {
+	if d, err := time.ParseDuration(os.Getenv("TOPIC_HALF_LIFE")); err == nil && d > 0 {
+		topicHalfLife = d
+	}
+
+	stmts := []string{
+		`ALTER TABLE user_topic_preferences ALTER COLUMN score TYPE DOUBLE PRECISION`,
+		`ALTER TABLE user_topic_preferences ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ`,
+	}
+	for _, stmt := range stmts {
+		if _, err := DB.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate topic scores: %w", err)
+		}
+	}
+
+	if err := migrateLegacyTopics(); err != nil {
+		return fmt.Errorf("date legacy topic scores: %w", err)
+	}
+
+	_, err := DB.Exec(`
+		ALTER TABLE user_topic_preferences
+			ALTER COLUMN updated_at SET DEFAULT NOW(),
+			ALTER COLUMN updated_at SET NOT NULL
+	`)
+	return err
+}
+
+// migrateLegacyTopics dates each undated score by the latest like, save
+// or dislike whose title produced that topic, so old interests start out
+// already decayed. Topics no feedback explains (onboarding picks) are
+// dated now.
+func migrateLegacyTopics() error {
+	rows, err := DB.Query(`SELECT DISTINCT user_id FROM user_topic_preferences WHERE updated_at IS NULL`)
+	if err != nil {
+		return err
+	}
+	var users []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(users) == 0 {
+		return err
+	}
+
+	rows, err = DB.Query(`
+		SELECT f.user_id, a.title, f.created_at
+		FROM feedback f
+		JOIN articles a ON a.link = f.article_id
+		WHERE f.user_id = ANY($1) AND f.action IN ('like', 'save', 'dislike')
+	`, pq.Array(users))
+	if err != nil {
+		return err
+	}
+	type key struct {
+		user  int
+		topic string
+	}
+	lastSeen := make(map[key]time.Time)
+	for rows.Next() {
+		var userID int
+		var title string
+		var at time.Time
+		if err := rows.Scan(&userID, &title, &at); err != nil {
+			rows.Close()
+			return err
+		}
+		for _, kw := range extractKeywords(title) {
+			if k := (key{userID, kw}); at.After(lastSeen[k]) {
+				lastSeen[k] = at
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var dated int64
+	for k, at := range lastSeen {
+		res, err := tx.Exec(`
+			UPDATE user_topic_preferences SET updated_at = $3
+			WHERE user_id = $1 AND topic = $2 AND updated_at IS NULL
+		`, k.user, k.topic, at)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		dated += n
+	}
+	res, err := tx.Exec(`UPDATE user_topic_preferences SET updated_at = NOW() WHERE updated_at IS NULL`)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	undated, _ := res.RowsAffected()
+	log.Printf("⏳ Dated legacy topic scores for %d users (%d topics from feedback, %d dated now)",
+		len(users), dated, undated)
+	return nil
+}
+
+// bumpTopic decays a user's topic score to now and adds weight to it.
+func bumpTopic(userID int, topic string, weight float64) error {
+	_, err := DB.Exec(`
+		INSERT INTO user_topic_preferences (user_id, topic, score, updated_at)
+		VALUES ($2, $3, $4, NOW())
+		ON CONFLICT (user_id, topic) DO UPDATE SET
+			score = user_topic_preferences.score * POWER(0.5,
+				EXTRACT(EPOCH FROM NOW() - user_topic_preferences.updated_at)::float8 / $1) + EXCLUDED.score,
+			updated_at = NOW()
+	`, topicHalfLife.Seconds(), userID, topic, weight)
+	return err
+}
+
+// decayedWeight is what weight, added at `at`, is worth now. Taking back a
+// reaction subtracts this, so un-liking an old like doesn't push the topic
+// below where it would have been without it.
+func decayedWeight(weight float64, at time.Time) float64 {
+	age := max(time.Since(at), 0)
+	return weight * math.Pow(0.5, float64(age)/float64(topicHalfLife))
+}
+
+// TopicScores returns a user's topics by current score, highest first.
+func TopicScores(ctx context.Context, userID, limit int) ([]TopicScore, error) {
+	rows, err := DB.QueryContext(ctx, `
+		SELECT topic, `+decayedScore+` AS current_score
+		FROM user_topic_preferences
+		WHERE user_id = $2
+		ORDER BY current_score DESC, topic
+		LIMIT $3
+	`, topicHalfLife.Seconds(), userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []TopicScore{}
+	for rows.Next() {
+		var t TopicScore
+		if err := rows.Scan(&t.Topic, &t.Score); err != nil {
+			return nil, err
+		}
+		t.Score = math.Round(t.Score*100) / 100
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
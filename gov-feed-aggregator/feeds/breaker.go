@@ -0,0 +1,83 @@
+package feeds
+
+import (
+	"errors"
+	"time"
+)
+
+/* ───────────────── CIRCUIT BREAKER ───────────────────────── */
+
+// A source's breaker opens after breakerThreshold consecutive failures and
+// stays open for a backoff that doubles on every further failure. Once the
+// window passes it goes half-open: exactly one probe fetch is let through,
+// and its outcome either closes the breaker or re-opens it.
+//
+// The state is derived from the health record (failure count + time of the
+// last error), so it survives restarts along with it.
+
+const (
+	breakerThreshold   = 3
+	breakerBaseBackoff = time.Minute
+	breakerMaxBackoff  = 6 * time.Hour
+
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+var errCircuitOpen = errors.New("circuit open")
+
+// breakerBackoff is how long the breaker stays open after `failures`
+// consecutive failures.
+func breakerBackoff(failures int) time.Duration {
+	backoff := breakerBaseBackoff
+	for i := breakerThreshold; i < failures; i++ {
+		backoff *= 2
+		if backoff >= breakerMaxBackoff {
+			return breakerMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// breakerState reports the breaker position for h and, when open, when the
+// next probe is allowed. Callers must hold healthMu.
+func breakerState(h *SourceHealth, now time.Time) (string, time.Time) {
+	if h == nil || h.ConsecutiveFailures < breakerThreshold || h.LastErrorAt == nil {
+		return breakerClosed, time.Time{}
+	}
+	retryAt := h.LastErrorAt.Add(breakerBackoff(h.ConsecutiveFailures))
+	if now.Before(retryAt) {
+		return breakerOpen, retryAt
+	}
+	return breakerHalfOpen, retryAt
+}
+
+// allowFetch reports whether src may be fetched right now. In the half-open
+// state it claims the single probe slot; recordFetch releases it.
+func allowFetch(src Source) bool {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	h := health[src.ID]
+	switch state, _ := breakerState(h, time.Now()); state {
+	case breakerOpen:
+		return false
+	case breakerHalfOpen:
+		if h.probing {
+			return false
+		}
+		h.probing = true
+	}
+	return true
+}
+
+// releaseProbe frees the half-open probe slot without recording an outcome,
+// for fetches abandoned because the caller went away.
+func releaseProbe(src Source) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if h := health[src.ID]; h != nil {
+		h.probing = false
+	}
+}
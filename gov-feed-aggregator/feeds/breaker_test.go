@@ -0,0 +1,114 @@
+package feeds
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBreakerBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{breakerThreshold, time.Minute},
+		{breakerThreshold + 1, 2 * time.Minute},
+		{breakerThreshold + 2, 4 * time.Minute},
+		{breakerThreshold + 8, 256 * time.Minute},
+		{breakerThreshold + 9, breakerMaxBackoff}, // 512m is past the cap
+		{breakerThreshold + 100, breakerMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := breakerBackoff(tt.failures); got != tt.want {
+			t.Errorf("breakerBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestBreakerState(t *testing.T) {
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+	tests := []struct {
+		name string
+		h    *SourceHealth
+		want string
+	}{
+		{"no record", nil, breakerClosed},
+		{"under the threshold", &SourceHealth{ConsecutiveFailures: breakerThreshold - 1, LastErrorAt: ago(0)}, breakerClosed},
+		{"just failed", &SourceHealth{ConsecutiveFailures: breakerThreshold, LastErrorAt: ago(0)}, breakerOpen},
+		{"backoff passed", &SourceHealth{ConsecutiveFailures: breakerThreshold, LastErrorAt: ago(2 * time.Minute)}, breakerHalfOpen},
+		{"longer backoff", &SourceHealth{ConsecutiveFailures: breakerThreshold + 2, LastErrorAt: ago(2 * time.Minute)}, breakerOpen},
+	}
+	for _, tt := range tests {
+		if got, _ := breakerState(tt.h, now); got != tt.want {
+			t.Errorf("%s: breakerState = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAllowFetchSingleHalfOpenProbe(t *testing.T) {
+	src := Source{ID: -1}
+	failedAt := time.Now().Add(-2 * breakerBaseBackoff)
+	healthMu.Lock()
+	health[src.ID] = &SourceHealth{SourceID: src.ID, ConsecutiveFailures: breakerThreshold, LastErrorAt: &failedAt}
+	healthMu.Unlock()
+	defer func() {
+		healthMu.Lock()
+		delete(health, src.ID)
+		healthMu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowFetch(src) {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 1 {
+		t.Fatalf("%d fetches allowed while half-open, want exactly one probe", allowed)
+	}
+
+	releaseProbe(src)
+	if !allowFetch(src) {
+		t.Errorf("probe slot not freed by releaseProbe")
+	}
+	if allowFetch(src) {
+		t.Errorf("second probe allowed after the slot was reclaimed")
+	}
+}
+
+func TestAllowFetchOpenAndClosed(t *testing.T) {
+	open, closed := Source{ID: -2}, Source{ID: -3}
+	failedAt := time.Now()
+	healthMu.Lock()
+	health[open.ID] = &SourceHealth{SourceID: open.ID, ConsecutiveFailures: breakerThreshold, LastErrorAt: &failedAt}
+	health[closed.ID] = &SourceHealth{SourceID: closed.ID, ConsecutiveFailures: 1, LastErrorAt: &failedAt}
+	healthMu.Unlock()
+	defer func() {
+		healthMu.Lock()
+		delete(health, open.ID)
+		delete(health, closed.ID)
+		healthMu.Unlock()
+	}()
+
+	if allowFetch(open) {
+		t.Errorf("fetch allowed through an open breaker")
+	}
+	for i := 0; i < 3; i++ {
+		if !allowFetch(closed) {
+			t.Errorf("fetch %d refused by a closed breaker", i)
+		}
+	}
+}
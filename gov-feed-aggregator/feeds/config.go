@@ -0,0 +1,64 @@
+package feeds
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+/* ───────────────── FETCH CONFIG ──────────────────────────── */
+
+// Tunables for the fetch pipeline, overridable from the environment:
+//
+//	FEED_FETCH_WORKERS   concurrent source fetches per ingest pass
+//	FEED_SOURCE_TIMEOUT  deadline for a single source (e.g. "8s")
+//	FEED_SEARCH_TIMEOUT  deadline for one search, and the most ?deadline= may ask for
+//	SEARCH_BACKEND       "postgres" (full-text search, default) or "memory"
+//	INDEX_SNAPSHOT_PATH  where the in-process search index is snapshotted
+//	EXPANSIONS_PATH      replacement for the built-in expansions.txt
+//	PERSONALIZE_WEIGHT   most a user's topics can add, as a share of the top score (0 turns it off)
+var (
+	fetchWorkers      = 8
+	sourceTimeout     = 10 * time.Second
+	searchTimeout     = 20 * time.Second
+	searchBackend     = "postgres"
+	indexSnapshotPath = "data/search-index.gob"
+	expansionsPath    = ""
+	personalizeWeight = 0.5
+)
+
+// LoadConfig reads the tunables above. Call it after the .env is loaded.
+func LoadConfig() {
+	fetchWorkers = envInt("FEED_FETCH_WORKERS", fetchWorkers)
+	sourceTimeout = envDuration("FEED_SOURCE_TIMEOUT", sourceTimeout)
+	searchTimeout = envDuration("FEED_SEARCH_TIMEOUT", searchTimeout)
+	if b := os.Getenv("SEARCH_BACKEND"); b == "postgres" || b == "memory" {
+		searchBackend = b
+	}
+	if p := os.Getenv("INDEX_SNAPSHOT_PATH"); p != "" {
+		indexSnapshotPath = p
+	}
+	expansionsPath = os.Getenv("EXPANSIONS_PATH")
+	personalizeWeight = envFloat("PERSONALIZE_WEIGHT", personalizeWeight)
+}
+
+func envInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+func envFloat(key string, def float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && f >= 0 {
+		return f
+	}
+	return def
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
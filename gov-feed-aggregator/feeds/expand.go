@@ -0,0 +1,108 @@
+package feeds
+
+import (
+	_ "embed"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+/* ───────────────── QUERY EXPANSION ───────────────────────── */
+
+// Each term or phrase in a query also matches its equivalents from the
+// expansion file (acronyms ⇄ spelled-out names, synonym sets), so "SBIR"
+// finds "Small Business Innovation Research". Expanded hits count for
+// expansionWeight of a literal hit. Everything is local; no network.
+
+const expansionWeight = 0.4
+
+//go:embed expansions.txt
+var defaultExpansions string
+
+// expansion is one alternative for a query term: its text (for tsquery),
+// its analyzed stems (for the in-process matcher) and how much a hit on
+// it counts next to a literal hit. Fuzzy ones come from fuzzy.go.
+type expansion struct {
+	Text   string
+	stems  []string
+	weight float64
+	fuzzy  bool
+}
+
+var (
+	expansions   = mustParseExpansions(defaultExpansions)
+	expansionsMu sync.RWMutex
+)
+
+// LoadExpansions swaps in the file at EXPANSIONS_PATH when one is set;
+// otherwise the built-in file stays in place.
+func LoadExpansions() error {
+	path := expansionsPath
+	if path == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m, err := parseExpansions(string(raw))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	expansionsMu.Lock()
+	expansions = m
+	expansionsMu.Unlock()
+	log.Printf("📖 Loaded %d expansion keys from %s", len(m), path)
+	return nil
+}
+
+func mustParseExpansions(raw string) map[string][]expansion {
+	m, err := parseExpansions(raw)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// parseExpansions maps every member of every set (keyed by its joined
+// stems) to the other members of that set.
+func parseExpansions(raw string) (map[string][]expansion, error) {
+	m := make(map[string][]expansion)
+	for n, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var set []expansion
+		for _, term := range strings.Split(line, ",") {
+			term = strings.ToLower(strings.TrimSpace(term))
+			if stems := analyze(term); len(stems) > 0 {
+				set = append(set, expansion{Text: term, stems: stems, weight: expansionWeight})
+			}
+		}
+		if len(set) < 2 {
+			return nil, fmt.Errorf("line %d: need at least two comma-separated terms", n+1)
+		}
+
+		for _, from := range set {
+			key := strings.Join(from.stems, " ")
+			for _, to := range set {
+				if strings.Join(to.stems, " ") != key {
+					m[key] = append(m[key], to)
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// expansionsFor returns the alternatives for an analyzed term or phrase.
+func expansionsFor(stems []string) []expansion {
+	expansionsMu.RLock()
+	defer expansionsMu.RUnlock()
+	return expansions[strings.Join(stems, " ")]
+}
@@ -0,0 +1,87 @@
+package feeds
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* ───────────────── FACETS ────────────────────────────────── */
+
+// Facets count the full matching set (not just the page) by category,
+// source domain and publication-date bucket.
+type Facets struct {
+	Category  []FacetCount `json:"category"`
+	Source    []FacetCount `json:"source"`
+	Published []FacetCount `json:"published"` // today | this_week | older
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// dateBuckets in display order. this_week is the 7 days before today;
+// undated items count as older.
+var dateBuckets = []string{"today", "this_week", "older"}
+
+func computeFacets(items []FeedItem, now time.Time) *Facets {
+	categories := map[string]int{}
+	domains := map[string]int{}
+	dates := map[string]int{}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekAgo := today.AddDate(0, 0, -7)
+
+	for _, it := range items {
+		if it.Category != "" {
+			categories[it.Category]++
+		}
+		if d := domainOf(it.Link); d != "" {
+			domains[d]++
+		}
+		switch {
+		case !it.Published.Before(today):
+			dates["today"]++
+		case !it.Published.Before(weekAgo):
+			dates["this_week"]++
+		default:
+			dates["older"]++
+		}
+	}
+
+	f := &Facets{
+		Category:  byCount(categories),
+		Source:    byCount(domains),
+		Published: []FacetCount{},
+	}
+	for _, b := range dateBuckets {
+		f.Published = append(f.Published, FacetCount{Value: b, Count: dates[b]})
+	}
+	return f
+}
+
+// domainOf is the link's host without "www.".
+func domainOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// byCount lists counts largest first, ties alphabetically.
+func byCount(m map[string]int) []FacetCount {
+	out := make([]FacetCount, 0, len(m))
+	for v, n := range m {
+		out = append(out, FacetCount{Value: v, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
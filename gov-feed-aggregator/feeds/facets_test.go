@@ -0,0 +1,67 @@
+package feeds
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestComputeFacets(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	items := []FeedItem{
+		{Link: "https://www.nsf.gov/a", Category: "Grant", Published: now.Add(-time.Hour)},
+		{Link: "https://nsf.gov/b", Category: "Grant", Published: now.AddDate(0, 0, -3)},
+		{Link: "https://www.army.mil/c", Category: "News", Published: now.AddDate(0, 0, -30)},
+		{Link: "https://www.army.mil/d"}, // no category, undated
+	}
+	f := computeFacets(items, now)
+
+	tests := []struct {
+		name string
+		got  []FacetCount
+		want string
+	}{
+		{"category", f.Category, "[{Grant 2} {News 1}]"},
+		{"source", f.Source, "[{army.mil 2} {nsf.gov 2}]"},
+		{"published", f.Published, "[{today 1} {this_week 1} {older 2}]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.got); got != tt.want {
+			t.Errorf("%s facets = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFacetsRespectFilters(t *testing.T) {
+	now := time.Now()
+	idx := newInvertedIndex()
+	idx.add(1, []FeedItem{
+		{Link: "https://www.nsf.gov/a", Title: "Drone research grant", Category: "Grant", Published: now},
+		{Link: "https://www.nsf.gov/b", Title: "Drone swarm grant awarded", Category: "Grant", Published: now.AddDate(0, 0, -2)},
+		{Link: "https://www.nsf.gov/c", Title: "Drone policy news", Category: "News", Published: now},
+		{Link: "https://www.army.mil/d", Title: "Army drone grant", Category: "Grant", Published: now},
+		{Link: "https://www.nsf.gov/e", Title: "Telescope grant", Category: "Grant", Published: now},
+	})
+
+	filters, err := ParseFilters("", "", []string{"Grant"}, []string{"nsf.gov"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := ParseQuery("drone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast = &QueryNode{Op: "and", Children: []*QueryNode{ast, filters.node()}}
+
+	r := nextPage(t, idx.search(ast, map[int]bool{1: true}, false), "", 1)
+	if len(r.Items) != 1 || r.NextCursor == "" {
+		t.Fatalf("page = %v (next %q), want one item and more to come", links(r.Items), r.NextCursor)
+	}
+	// both filtered matches, not just the page; none of the filtered-out ones
+	if got := fmt.Sprint(r.Facets.Category); got != "[{Grant 2}]" {
+		t.Errorf("category facets = %s, want [{Grant 2}]", got)
+	}
+	if got := fmt.Sprint(r.Facets.Source); got != "[{nsf.gov 2}]" {
+		t.Errorf("source facets = %s, want [{nsf.gov 2}]", got)
+	}
+}
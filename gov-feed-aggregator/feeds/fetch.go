@@ -0,0 +1,93 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── CONDITIONAL HTTP FETCH ────────────────── */
+
+// cacheValidators are the HTTP validators a source last sent us.
+type cacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+var (
+	feedClient = &http.Client{Timeout: 30 * time.Second}
+
+	validators   = make(map[string]cacheValidators) // source URL → validators
+	validatorsMu sync.Mutex
+)
+
+// fetchFeed downloads and parses one source, sending If-None-Match /
+// If-Modified-Since when we have validators for it. notModified is true
+// (and feed nil) when the host answered 304. next holds the response's
+// validators when they changed; the caller saves them (saveValidators)
+// only once the items are stored, or a failed write would be answered
+// with 304s from then on.
+func fetchFeed(ctx context.Context, src Source) (feed *gofeed.Feed, next *cacheValidators, notModified bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	req.Header.Set("User-Agent", "gov-feed-aggregator/1.0")
+
+	validatorsMu.Lock()
+	v := validators[src.URL]
+	validatorsMu.Unlock()
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := feedClient.Do(req)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil, true, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, false, fmt.Errorf("http %d", resp.StatusCode)
+	}
+
+	feed, err = gofeed.NewParser().Parse(resp.Body)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	got := cacheValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if got != v {
+		next = &got
+	}
+	return feed, next, false, nil
+}
+
+// saveValidators remembers validators in memory and on the source row so
+// they survive restarts.
+func saveValidators(src Source, v cacheValidators) {
+	validatorsMu.Lock()
+	validators[src.URL] = v
+	validatorsMu.Unlock()
+
+	_, err := auth.DB.Exec(`UPDATE sources SET etag = $2, last_modified = $3 WHERE id = $1`,
+		src.ID, v.ETag, v.LastModified)
+	if err != nil {
+		log.Printf("⚠️ Could not persist validators for %s: %v", src.URL, err)
+	}
+}
@@ -0,0 +1,113 @@
+package feeds
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ───────────────── STRUCTURED FILTERS ────────────────────── */
+
+// SearchFilters are the explicit /feed filters. They compile to the same
+// nodes as the category: / source: / after: / before: query fields, so
+// both search backends apply them.
+type SearchFilters struct {
+	From       time.Time // inclusive
+	To         time.Time // exclusive
+	Categories []string  // any of, case-insensitive (classifier output)
+	Sources    []string  // any of; source name or link host contains
+}
+
+// ParseFilters reads from / to (YYYY-MM-DD, or an age like "7d" / "12h")
+// plus the category and source lists. Each list entry may itself be
+// comma-separated. A date-only to covers that whole day.
+func ParseFilters(from, to string, categories, sources []string) (SearchFilters, error) {
+	var f SearchFilters
+	var err error
+
+	if from != "" {
+		if f.From, err = parseFilterTime(from, false); err != nil {
+			return f, fmt.Errorf("from: %v", err)
+		}
+	}
+	if to != "" {
+		if f.To, err = parseFilterTime(to, true); err != nil {
+			return f, fmt.Errorf("to: %v", err)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return f, fmt.Errorf("from must be before to")
+	}
+
+	f.Categories = splitFilterList(categories)
+	f.Sources = splitFilterList(sources)
+	return f, nil
+}
+
+func parseFilterTime(raw string, endOfDay bool) (time.Time, error) {
+	if d, err := time.Parse("2006-01-02", raw); err == nil {
+		if endOfDay {
+			d = d.AddDate(0, 0, 1)
+		}
+		return d, nil
+	}
+	if strings.HasSuffix(raw, "d") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(raw, "d")); err == nil && n > 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("want YYYY-MM-DD or an age like 7d, got %q", raw)
+}
+
+func splitFilterList(vals []string) []string {
+	var out []string
+	for _, v := range vals {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// IsZero reports whether no filter is set.
+func (f SearchFilters) IsZero() bool {
+	return f.From.IsZero() && f.To.IsZero() && len(f.Categories) == 0 && len(f.Sources) == 0
+}
+
+// node compiles the filters to a query AST, or nil when there are none.
+func (f SearchFilters) node() *QueryNode {
+	var and []*QueryNode
+
+	if !f.From.IsZero() {
+		and = append(and, &QueryNode{Op: "term", Field: "after", Value: f.From.Format(time.RFC3339), date: f.From})
+	}
+	if !f.To.IsZero() {
+		and = append(and, &QueryNode{Op: "term", Field: "before", Value: f.To.Format(time.RFC3339), date: f.To})
+	}
+	anyOf := func(field string, vals []string) {
+		if len(vals) == 0 {
+			return
+		}
+		or := &QueryNode{Op: "or"}
+		for _, v := range vals {
+			or.Children = append(or.Children, &QueryNode{Op: "term", Field: field, Value: v})
+		}
+		and = append(and, or)
+	}
+	anyOf("category", f.Categories)
+	anyOf("source", f.Sources)
+
+	switch len(and) {
+	case 0:
+		return nil
+	case 1:
+		return and[0]
+	}
+	return &QueryNode{Op: "and", Children: and}
+}
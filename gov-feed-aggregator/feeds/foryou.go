@@ -0,0 +1,116 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── FOR YOU ───────────────────────────────── */
+
+// The home feed for a logged-in user who hasn't searched: the latest
+// stored articles minus the ones they hid or disliked, scored by topic
+// affinity and recency. The ranking is then re-done greedily so one busy
+// source can't fill the top: each further pick from a source is worth
+// forYouSourceDecay times less.
+
+const (
+	forYouCandidates    = 500
+	forYouWindow        = 14 * 24 * time.Hour
+	forYouHalfLife      = 48 * time.Hour
+	forYouTopicWeight   = 0.6
+	forYouRecencyWeight = 0.4
+	forYouSourceDecay   = 0.7
+)
+
+// ForYou builds the home feed of userID. Items come ranked, with Score set
+// so that sort=relevance paging keeps the order. It takes no filters: with
+// any query or filter, /feed searches instead.
+func ForYou(ctx context.Context, userID int) (*SearchResult, error) {
+	start := time.Now()
+	srcs, meta := storedCorpus()
+
+	ids := make([]int64, len(srcs))
+	for i, s := range srcs {
+		ids[i] = int64(s.ID)
+	}
+
+	rows, err := auth.DB.QueryContext(ctx, `
+		SELECT a.link, a.title, a.description, a.published, a.category, a.source,
+		       a.source_id, COALESCE(a.published, a.ingested_at)
+		FROM articles a
+		WHERE a.source_id = ANY($1)
+		  AND COALESCE(a.published, a.ingested_at) > $3
+		  AND NOT EXISTS (
+		      SELECT 1 FROM feedback f
+		      WHERE f.user_id = $2 AND f.article_id = a.link AND f.action IN ('hide', 'dislike'))
+		ORDER BY COALESCE(a.published, a.ingested_at) DESC
+		LIMIT $4
+	`, pq.Array(ids), userID, start.Add(-forYouWindow), forYouCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("load home feed: %w", err)
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		item     FeedItem
+		sourceID int
+		at       time.Time
+		base     float64
+	}
+	terms := topicTerms(userTopics(ctx, userID))
+
+	var cands []candidate
+	for rows.Next() {
+		var c candidate
+		var published sql.NullTime
+		if err := rows.Scan(&c.item.Link, &c.item.Title, &c.item.Description, &published,
+			&c.item.Category, &c.item.Source, &c.sourceID, &c.at); err != nil {
+			return nil, err
+		}
+		c.item.Published = published.Time
+
+		sum, names := matchTopics(terms, &c.item)
+		topic := forYouTopicWeight * topicAffinity(sum)
+		age := max(start.Sub(c.at), 0)
+		recency := forYouRecencyWeight * math.Pow(0.5, float64(age)/float64(forYouHalfLife))
+		c.base = topic + recency
+		c.item.Personalization = &Personalization{Boost: math.Round(topic*1000) / 1000, Topics: names}
+		cands = append(cands, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	/* greedy source diversity; picked scores never increase, so Score order is the pick order */
+	items := make([]FeedItem, 0, len(cands))
+	picked := make([]bool, len(cands))
+	perSource := make(map[int]int)
+	for range cands {
+		best, bestScore := -1, 0.0
+		for i, c := range cands {
+			if picked[i] {
+				continue
+			}
+			s := c.base * math.Pow(forYouSourceDecay, float64(perSource[c.sourceID]))
+			if best < 0 || s > bestScore ||
+				(s == bestScore && c.at.After(cands[best].at)) {
+				best, bestScore = i, s
+			}
+		}
+		picked[best] = true
+		perSource[cands[best].sourceID]++
+		it := cands[best].item
+		it.Score = bestScore
+		items = append(items, it)
+	}
+
+	meta.ElapsedMs = time.Since(start).Milliseconds()
+	fmt.Printf("🏠 For You feed for user %d | %d items | %v\n", userID, len(items), time.Since(start))
+	return &SearchResult{Items: items, Meta: meta}, nil
+}
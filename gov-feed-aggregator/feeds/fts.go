@@ -0,0 +1,275 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lib/pq"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── POSTGRES FULL-TEXT SEARCH ─────────────── */
+
+// Title, description and content are folded into one weighted tsvector
+// (A/B/C) so ts_rank favours title hits; quick mode and title: use a
+// title-only vector. Both are generated columns, kept current by Postgres.
+var ftsSchema = []string{
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+			setweight(to_tsvector('english', coalesce(content, '')), 'C')
+		) STORED`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS title_vector tsvector
+		GENERATED ALWAYS AS (to_tsvector('english', coalesce(title, ''))) STORED`,
+	`CREATE INDEX IF NOT EXISTS articles_search_vector_idx ON articles USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS articles_title_vector_idx ON articles USING GIN (title_vector)`,
+}
+
+const headlineOpts = `StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2`
+
+// ftsQuery compiles a QueryNode into a SQL predicate plus its parameters.
+type ftsQuery struct {
+	args    []interface{}
+	deep    bool
+	noFuzzy bool // leave out fuzzy expansions (for the "as typed" check)
+}
+
+func (q *ftsQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// tsquery returns the tsquery expression for a term or phrase node.
+func (q *ftsQuery) tsquery(n *QueryNode) string {
+	fn := "plainto_tsquery"
+	if n.Op == "phrase" {
+		fn = "phraseto_tsquery"
+	}
+	return fmt.Sprintf("%s('english', %s)", fn, q.arg(n.Value))
+}
+
+// altTsquery is the phrase tsquery for one expansion.
+func (q *ftsQuery) altTsquery(a expansion) string {
+	return fmt.Sprintf("phraseto_tsquery('english', %s)", q.arg(a.Text))
+}
+
+// textMatch matches vec against n or any of its expansions. A term that is
+// all stopwords ("of", "the") compiles to an empty tsquery, which matches
+// nothing, so it is treated as always true instead: "department of
+// defense" then needs only department and defense.
+func (q *ftsQuery) textMatch(vec string, n *QueryNode) string {
+	tq := q.tsquery(n)
+	parts := []string{"numnode(" + tq + ") = 0", vec + " @@ " + tq}
+	for _, a := range n.alts {
+		if !q.noFuzzy || !a.fuzzy {
+			parts = append(parts, vec+" @@ "+q.altTsquery(a))
+		}
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// vector is the tsvector a term or phrase searches: the title for title:
+// terms and quick searches, everything for deep ones.
+func (q *ftsQuery) vector(n *QueryNode) string {
+	if q.deep && n.Field == "" {
+		return "search_vector"
+	}
+	return "title_vector"
+}
+
+func (q *ftsQuery) predicate(n *QueryNode) string {
+	switch n.Op {
+	case "and", "or":
+		if len(n.Children) == 0 {
+			return "TRUE"
+		}
+		parts := make([]string, len(n.Children))
+		for i, c := range n.Children {
+			parts[i] = q.predicate(c)
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(n.Op)+" ") + ")"
+	case "not":
+		return "NOT (" + q.predicate(n.Children[0]) + ")"
+	}
+
+	switch n.Field {
+	case "", "title":
+		return q.textMatch(q.vector(n), n)
+	case "source":
+		// strpos, not LIKE: a value's % and _ are literal, not wildcards
+		p := q.arg(n.Value)
+		return fmt.Sprintf("(strpos(lower(source), %s) > 0 OR strpos(lower(split_part(link, '/', 3)), %s) > 0)", p, p)
+	case "category":
+		return "lower(category) = " + q.arg(n.Value)
+	case "after":
+		return "published >= " + q.arg(n.date)
+	case "before":
+		return "published < " + q.arg(n.date)
+	}
+	return "FALSE"
+}
+
+// rankQuery ORs together every positive term so ts_rank / ts_headline have
+// something to score and mark. Each term is ranked against the vector it
+// was matched on (see vector). rank scores literal hits in full and
+// expansions at their weight; markQuery covers both. Both are empty when
+// the query is all filters.
+func (q *ftsQuery) rankQuery(ast *QueryNode) (rank, markQuery string) {
+	var parts, marks, ranks, vecs []string
+	byVec := make(map[string][]string)
+	for _, t := range ast.positiveTextNodes() {
+		vec, tq := q.vector(t), q.tsquery(t)
+		if _, ok := byVec[vec]; !ok {
+			vecs = append(vecs, vec)
+		}
+		byVec[vec] = append(byVec[vec], tq)
+		parts = append(parts, tq)
+		for _, a := range t.alts {
+			tq := q.altTsquery(a)
+			marks = append(marks, tq)
+			ranks = append(ranks, fmt.Sprintf("%g * ts_rank(%s, %s)", a.weight, vec, tq))
+		}
+	}
+	if len(parts) == 0 {
+		return "", ""
+	}
+	literal := make([]string, len(vecs))
+	for i, vec := range vecs {
+		literal[i] = fmt.Sprintf("ts_rank(%s, (%s))", vec, strings.Join(byVec[vec], " || "))
+	}
+	rank = strings.Join(literal, " + ")
+	if len(ranks) > 0 {
+		rank = "GREATEST(" + rank + ", " + strings.Join(ranks, ", ") + ")"
+	}
+	return rank, "(" + strings.Join(append(parts, marks...), " || ") + ")"
+}
+
+// ftsSearch runs the query against the stored articles of srcs, ranked by
+// ts_rank (plus the usual small recency bump). ts_headline is too slow to
+// run on every match, so the <mark>ed headlines come from the returned
+// highlight func, which Paginate calls on the served page only.
+func ftsSearch(ctx context.Context, ast *QueryNode, srcs []Source, deep bool) ([]FeedItem, func([]FeedItem), error) {
+	ids := make([]int64, len(srcs))
+	for i, s := range srcs {
+		ids[i] = int64(s.ID)
+	}
+
+	q := &ftsQuery{deep: deep}
+	idsArg := q.arg(pq.Array(ids))
+	where := q.predicate(ast)
+	literal := "TRUE"
+	if ast.hasFuzzy() {
+		q.noFuzzy = true
+		literal = q.predicate(ast)
+		q.noFuzzy = false
+	}
+	rank, rq := q.rankQuery(ast)
+
+	inTitle, inDesc, inContent := "FALSE", "FALSE", "FALSE"
+	if rank == "" {
+		rank = "0"
+	}
+	if rq != "" {
+		inTitle = "title_vector @@ " + rq
+		if deep {
+			inDesc = fmt.Sprintf("to_tsvector('english', description) @@ %s", rq)
+			inContent = fmt.Sprintf("to_tsvector('english', content) @@ %s", rq)
+		}
+	}
+
+	stmt := fmt.Sprintf(`
+		SELECT link, title, description, content, published, category, source,
+		       %s, %s, %s, %s,
+		       %s + CASE WHEN published > NOW() - INTERVAL '24 hours' THEN 0.05
+		                 WHEN published > NOW() - INTERVAL '72 hours' THEN 0.02
+		                 ELSE 0 END AS score
+		FROM articles
+		WHERE source_id = ANY(%s) AND %s
+		ORDER BY score DESC, published DESC NULLS LAST
+	`, inTitle, inDesc, inContent, literal, rank, idsArg, where)
+
+	rows, err := auth.DB.QueryContext(ctx, stmt, q.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("full-text search: %w", err)
+	}
+	defer rows.Close()
+
+	items := []FeedItem{}
+	for rows.Next() {
+		var it FeedItem
+		var published sql.NullTime
+		var t, d, c, lit bool
+		if err := rows.Scan(&it.Link, &it.Title, &it.Description, &it.Content, &published,
+			&it.Category, &it.Source, &t, &d, &c, &lit, &it.Score); err != nil {
+			return nil, nil, err
+		}
+		it.Published = published.Time
+		it.fuzzyOnly = !lit
+		if t {
+			it.MatchedFields = append(it.MatchedFields, "title")
+		}
+		if d {
+			it.MatchedFields = append(it.MatchedFields, "description")
+		}
+		if c {
+			it.MatchedFields = append(it.MatchedFields, "content")
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var highlight func([]FeedItem)
+	if rq != "" {
+		highlight = func(page []FeedItem) { ftsHighlight(ctx, ast, page) }
+	}
+	return items, highlight, nil
+}
+
+// ftsHighlight fills in the <mark>ed title and snippet of each item. A
+// failure only costs the highlights.
+func ftsHighlight(ctx context.Context, ast *QueryNode, items []FeedItem) {
+	if len(items) == 0 {
+		return
+	}
+	links := make([]string, len(items))
+	for i := range items {
+		links[i] = items[i].Link
+	}
+
+	q := &ftsQuery{}
+	_, rq := q.rankQuery(ast)
+	rows, err := auth.DB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT link,
+		       ts_headline('english', title, %[1]s, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>'),
+		       ts_headline('english', regexp_replace(description, '<[^>]*>', ' ', 'g'), %[1]s, '%[2]s')
+		FROM articles
+		WHERE link = ANY(%[3]s)
+	`, rq, headlineOpts, q.arg(pq.Array(links))), q.args...)
+	if err != nil {
+		log.Printf("⚠️ Could not highlight results: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	byLink := make(map[string]*Highlights, len(items))
+	for rows.Next() {
+		var link string
+		var hl Highlights
+		if err := rows.Scan(&link, &hl.Title, &hl.Snippet); err != nil {
+			log.Printf("⚠️ Could not highlight results: %v", err)
+			return
+		}
+		byLink[link] = &hl
+	}
+	for i := range items {
+		if hl, ok := byLink[items[i].Link]; ok {
+			items[i].Highlights = hl
+		}
+	}
+}
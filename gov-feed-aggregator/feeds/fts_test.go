@@ -0,0 +1,55 @@
+package feeds
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFTSSourcePredicateIsLiteral(t *testing.T) {
+	ast, err := ParseQuery(`source:100%_off\x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &ftsQuery{}
+	where := q.predicate(ast)
+	if strings.Contains(where, "LIKE") {
+		t.Errorf("predicate %s uses LIKE, where %% and _ are wildcards", where)
+	}
+	if got := fmt.Sprint(q.args); got != `[100%_off\x]` {
+		t.Errorf("args = %s, want the value as typed", got)
+	}
+}
+
+func TestFTSRankUsesMatchedVector(t *testing.T) {
+	tests := []struct {
+		query string
+		deep  bool
+		want  []string // vectors ranked against
+	}{
+		{"hypersonic missile", false, []string{"ts_rank(title_vector"}},
+		{"hypersonic missile", true, []string{"ts_rank(search_vector"}},
+		{"title:hypersonic missile", true, []string{"ts_rank(title_vector", "ts_rank(search_vector"}},
+		{"title:hypersonic", true, []string{"ts_rank(title_vector"}},
+	}
+	for _, tt := range tests {
+		ast, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := &ftsQuery{deep: tt.deep}
+		where := q.predicate(ast)
+		rank, _ := q.rankQuery(ast)
+		for _, vec := range []string{"title_vector", "search_vector"} {
+			ranked := strings.Contains(rank, "ts_rank("+vec)
+			if ranked != strings.Contains(where, vec) {
+				t.Errorf("%q (deep %v): rank %s doesn't match predicate %s on %s", tt.query, tt.deep, rank, where, vec)
+			}
+		}
+		for _, w := range tt.want {
+			if !strings.Contains(rank, w) {
+				t.Errorf("%q (deep %v): rank %s, want %s", tt.query, tt.deep, rank, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package feeds
+
+import (
+	"regexp"
+	"sort"
+)
+
+/* ───────────────── FUZZY MATCHING ────────────────────────── */
+
+// A query word the index has never seen ("hypersonc", "ukrane") picks up
+// the closest words of the indexed vocabulary as extra alternatives, at a
+// penalty per edit. Known words are left alone so typo tolerance can't
+// drift a correct query ("army" → "arms").
+
+const fuzzyMaxCandidates = 3
+
+// fuzzyWeights[d] is the score weight of a match d edits away.
+var fuzzyWeights = []float64{1, 0.35, 0.2}
+
+// fuzzyBudget is how many edits a word of n letters may be off by.
+func fuzzyBudget(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// addFuzzy attaches vocabulary neighbours to the unknown single-word terms
+// of ast.
+func (idx *invertedIndex) addFuzzy(ast *QueryNode) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	for _, t := range ast.positiveTextNodes() {
+		if t.Op != "term" || len(t.stems) != 1 || len(t.alts) > 0 {
+			continue
+		}
+		s := t.stems[0]
+		budget := fuzzyBudget(len(s))
+		if budget == 0 || stopwords[s] || !isAlpha(s) || idx.Title[s] != nil || idx.Body[s] != nil {
+			continue
+		}
+
+		type candidate struct {
+			word string
+			dist int
+			df   int
+		}
+		var cands []candidate
+		consider := func(postings map[string]map[int]int) {
+			for w, p := range postings {
+				if abs(len(w)-len(s)) > budget || !isAlpha(w) {
+					continue
+				}
+				if d := editDistance(s, w, budget); d <= budget {
+					cands = append(cands, candidate{w, d, len(p)})
+				}
+			}
+		}
+		consider(idx.Title)
+		consider(idx.Body)
+
+		sort.Slice(cands, func(i, j int) bool {
+			a, b := cands[i], cands[j]
+			if a.dist != b.dist {
+				return a.dist < b.dist
+			}
+			if a.df != b.df {
+				return a.df > b.df
+			}
+			return a.word < b.word
+		})
+
+		seen := map[string]bool{}
+		for _, c := range cands {
+			if seen[c.word] {
+				continue // same word from title and body postings
+			}
+			seen[c.word] = true
+			text := idx.Surface[c.word]
+			if text == "" {
+				text = c.word
+			}
+			t.alts = append(t.alts, expansion{Text: text, stems: []string{c.word},
+				weight: fuzzyWeights[c.dist], fuzzy: true})
+			if len(seen) == fuzzyMaxCandidates {
+				break
+			}
+		}
+	}
+}
+
+// hasFuzzy reports whether addFuzzy corrected any term.
+func (n *QueryNode) hasFuzzy() bool {
+	for _, t := range n.positiveTextNodes() {
+		for _, a := range t.alts {
+			if a.fuzzy {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// didYouMean rewrites query with each corrected term replaced by its
+// closest vocabulary word.
+func didYouMean(query string, ast *QueryNode) string {
+	out := query
+	for _, t := range ast.positiveTextNodes() {
+		for _, a := range t.alts {
+			if a.fuzzy {
+				re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(t.Value) + `\b`)
+				out = re.ReplaceAllLiteralString(out, a.Text)
+				break
+			}
+		}
+	}
+	if out == query {
+		return ""
+	}
+	return out
+}
+
+// editDistance is the optimal-string-alignment distance between a and b
+// (insert, delete, substitute, swap adjacent), or limit+1 once it is
+// certain to exceed limit.
+func editDistance(a, b string, limit int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
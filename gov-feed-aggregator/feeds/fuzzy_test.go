@@ -0,0 +1,110 @@
+package feeds
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"army", "army", 2, 0},
+		{"ukrane", "ukraine", 1, 1},
+		{"hypersonc", "hypersonic", 2, 1},
+		{"form", "from", 1, 1}, // adjacent swap is one edit
+		{"kitten", "sitting", 3, 3},
+		{"", "abc", 5, 3},
+		{"abc", "xyz", 1, 2}, // past the limit: limit+1
+		{"missile", "mission", 1, 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyBudget(t *testing.T) {
+	for n, want := range map[int]int{1: 0, 3: 0, 4: 1, 7: 1, 8: 2, 12: 2} {
+		if got := fuzzyBudget(n); got != want {
+			t.Errorf("fuzzyBudget(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func fuzzyIndex() *invertedIndex {
+	idx := newInvertedIndex()
+	idx.add(1, []FeedItem{
+		{Link: "https://example.mil/a", Title: "Hypersonic missile test"},
+		{Link: "https://example.mil/b", Title: "Ukraine aid package"},
+		{Link: "https://example.mil/c", Title: "Army arms deal"},
+	})
+	return idx
+}
+
+// alts lists the fuzzy alternatives addFuzzy attached to each term.
+func alts(ast *QueryNode) string {
+	var out []string
+	for _, t := range ast.positiveTextNodes() {
+		for _, a := range t.alts {
+			if a.fuzzy {
+				out = append(out, fmt.Sprintf("%s→%s@%g", t.Value, a.Text, a.weight))
+			}
+		}
+	}
+	return fmt.Sprint(out)
+}
+
+func TestAddFuzzy(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"hypersonc", "[hypersonc→hypersonic@0.35]"}, // 9 letters: one edit
+		{"hyprsonc", "[hyprsonc→hypersonic@0.2]"},    // 8 letters: two edits
+		{"ukrane", "[ukrane→ukraine@0.35]"},          // 6 letters: one edit
+		{"ukrxne", "[]"},                             // 6 letters can't be two edits off
+		{"amry", "[amry→army@0.35]"},                 // swap
+		{"arx", "[]"},                                // under four letters: never corrected
+		{"army", "[]"},                               // known words are left alone
+		{"-ukrane", "[]"},                            // exclusions aren't corrected
+		{`"ukrane aid"`, "[]"},                       // nor phrases
+	}
+	idx := fuzzyIndex()
+	for _, tt := range tests {
+		ast, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.addFuzzy(ast)
+		if got := alts(ast); got != tt.want {
+			t.Errorf("addFuzzy(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"hypersonc missile", "hypersonic missile"},
+		{"Ukrane aid -russia", "ukraine aid -russia"},
+		{"hypersonc ukrane", "hypersonic ukraine"},
+		{"hypersonic missile", ""}, // nothing corrected
+	}
+	idx := fuzzyIndex()
+	for _, tt := range tests {
+		ast, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.addFuzzy(ast)
+		if got := didYouMean(tt.query, ast); got != tt.want {
+			t.Errorf("didYouMean(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
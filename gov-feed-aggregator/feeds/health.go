@@ -0,0 +1,186 @@
+package feeds
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── SOURCE HEALTH ─────────────────────────── */
+
+// SourceHealth is the fetch track record of one source.
+type SourceHealth struct {
+	SourceID            int        `json:"source_id"`
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	Enabled             bool       `json:"enabled"`
+	Status              string     `json:"status"` // ok | failing | unknown
+	LastSuccess         *time.Time `json:"last_success"`
+	LastError           string     `json:"last_error"`
+	LastErrorAt         *time.Time `json:"last_error_at"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LatencyMs           int64      `json:"latency_ms"`
+	ItemCount           int        `json:"item_count"`
+	Breaker             string     `json:"breaker"` // closed | open | half_open
+	RetryAt             *time.Time `json:"retry_at"`
+
+	probing bool // half-open probe in flight
+}
+
+var (
+	health   = make(map[int]*SourceHealth) // source id → health
+	healthMu sync.Mutex
+)
+
+// InitHealth creates the `source_health` table and loads the last known
+// state so a restart doesn't make dead feeds look healthy.
+func InitHealth() error {
+	_, err := auth.DB.Exec(`
+		CREATE TABLE IF NOT EXISTS source_health (
+			source_id            INTEGER PRIMARY KEY REFERENCES sources (id) ON DELETE CASCADE,
+			last_success_at      TIMESTAMPTZ,
+			last_error           TEXT NOT NULL DEFAULT '',
+			last_error_at        TIMESTAMPTZ,
+			consecutive_failures INTEGER NOT NULL DEFAULT 0,
+			latency_ms           BIGINT NOT NULL DEFAULT 0,
+			item_count           INTEGER NOT NULL DEFAULT 0,
+			updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("create source_health table: %w", err)
+	}
+
+	rows, err := auth.DB.Query(`
+		SELECT source_id, last_success_at, last_error, last_error_at,
+		       consecutive_failures, latency_ms, item_count
+		FROM source_health
+	`)
+	if err != nil {
+		return fmt.Errorf("load source health: %w", err)
+	}
+	defer rows.Close()
+
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	for rows.Next() {
+		var h SourceHealth
+		var lastSuccess, lastErrorAt sql.NullTime
+		if err := rows.Scan(&h.SourceID, &lastSuccess, &h.LastError, &lastErrorAt,
+			&h.ConsecutiveFailures, &h.LatencyMs, &h.ItemCount); err != nil {
+			return err
+		}
+		if lastSuccess.Valid {
+			h.LastSuccess = &lastSuccess.Time
+		}
+		if lastErrorAt.Valid {
+			h.LastErrorAt = &lastErrorAt.Time
+		}
+		health[h.SourceID] = &h
+	}
+	return rows.Err()
+}
+
+// recordFetch updates the health of src after one fetch attempt. itemCount
+// is ignored for 304s (pass -1) so the last real count is kept.
+func recordFetch(src Source, latency time.Duration, itemCount int, fetchErr error) {
+	now := time.Now()
+
+	healthMu.Lock()
+	h, ok := health[src.ID]
+	if !ok {
+		h = &SourceHealth{SourceID: src.ID}
+		health[src.ID] = h
+	}
+	h.probing = false
+	h.LatencyMs = latency.Milliseconds()
+	if fetchErr != nil {
+		h.ConsecutiveFailures++
+		h.LastError = fetchErr.Error()
+		h.LastErrorAt = &now
+	} else {
+		h.ConsecutiveFailures = 0
+		h.LastSuccess = &now
+		if itemCount >= 0 {
+			h.ItemCount = itemCount
+		}
+	}
+	snap := *h
+	healthMu.Unlock()
+
+	_, err := auth.DB.Exec(`
+		INSERT INTO source_health (source_id, last_success_at, last_error, last_error_at,
+		                           consecutive_failures, latency_ms, item_count, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (source_id) DO UPDATE SET
+			last_success_at      = EXCLUDED.last_success_at,
+			last_error           = EXCLUDED.last_error,
+			last_error_at        = EXCLUDED.last_error_at,
+			consecutive_failures = EXCLUDED.consecutive_failures,
+			latency_ms           = EXCLUDED.latency_ms,
+			item_count           = EXCLUDED.item_count,
+			updated_at           = NOW()
+	`, snap.SourceID, snap.LastSuccess, snap.LastError, snap.LastErrorAt,
+		snap.ConsecutiveFailures, snap.LatencyMs, snap.ItemCount)
+	if err != nil {
+		log.Printf("⚠️ Could not persist health for %s: %v", src.URL, err)
+	}
+}
+
+// sourceHealthReport joins the registry with the recorded health.
+func sourceHealthReport() []SourceHealth {
+	registryMu.RLock()
+	srcs := make([]Source, len(registry))
+	copy(srcs, registry)
+	registryMu.RUnlock()
+
+	now := time.Now()
+
+	healthMu.Lock()
+	out := make([]SourceHealth, 0, len(srcs))
+	for _, src := range srcs {
+		h := SourceHealth{SourceID: src.ID}
+		if known, ok := health[src.ID]; ok {
+			h = *known
+		}
+		h.Name = src.Name
+		h.URL = src.URL
+		h.Enabled = src.Enabled
+
+		switch {
+		case h.ConsecutiveFailures > 0:
+			h.Status = "failing"
+		case h.LastSuccess != nil:
+			h.Status = "ok"
+		default:
+			h.Status = "unknown"
+		}
+
+		state, retryAt := breakerState(&h, now)
+		h.Breaker = state
+		if state != breakerClosed {
+			h.RetryAt = &retryAt
+		}
+		out = append(out, h)
+	}
+	healthMu.Unlock()
+
+	/* worst first */
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].ConsecutiveFailures != out[j].ConsecutiveFailures {
+			return out[i].ConsecutiveFailures > out[j].ConsecutiveFailures
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func SourceHealthHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, sourceHealthReport())
+}
@@ -0,0 +1,135 @@
+package feeds
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+/* ───────────────── HIGHLIGHTS ────────────────────────────── */
+
+// The in-process backend marks the same analyzed terms the matcher used:
+// a word is marked when its stem lines up with a positive term (or a run
+// of words with a phrase), so "contracting" is marked for "contracts".
+// Expansions are marked too.
+// The Postgres backend gets the equivalent from ts_headline.
+
+const snippetWords = 35
+
+var wordRe = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+type textSpan struct{ start, end int }
+
+// markSpans finds the byte ranges of text that match any of terms.
+func markSpans(text string, terms []*QueryNode) (words [][]int, spans []textSpan) {
+	words = wordRe.FindAllStringIndex(text, -1)
+	stems := make([]string, len(words))
+	for i, w := range words {
+		stems[i] = stem(strings.ToLower(text[w[0]:w[1]]))
+	}
+
+	marked := make([]bool, len(words))
+	mark := func(seq []string) {
+		n := len(seq)
+		for i := 0; i+n <= len(stems); i++ {
+			if containsSeq(stems[i:i+n], seq) {
+				for j := i; j < i+n; j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+	for _, t := range terms {
+		mark(t.stems)
+		for _, a := range t.alts {
+			mark(a.stems)
+		}
+	}
+
+	// adjacent marked words (a phrase) become one span
+	for i := 0; i < len(words); i++ {
+		if !marked[i] {
+			continue
+		}
+		j := i
+		for j+1 < len(words) && marked[j+1] {
+			j++
+		}
+		spans = append(spans, textSpan{words[i][0], words[j][1]})
+		i = j
+	}
+	return words, spans
+}
+
+// renderMarked escapes text[from:to] and wraps the spans inside it in <mark>.
+func renderMarked(text string, spans []textSpan, from, to int) string {
+	var b strings.Builder
+	pos := from
+	for _, s := range spans {
+		if s.end <= from || s.start >= to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:s.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[s.start:s.end]))
+		b.WriteString("</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	return b.String()
+}
+
+// highlightItem builds the title and a description snippet centred on
+// the first match (or the opening words when only the title matched).
+func highlightItem(it *FeedItem, terms []*QueryNode) *Highlights {
+	h := &Highlights{}
+
+	_, spans := markSpans(it.Title, terms)
+	h.Title = renderMarked(it.Title, spans, 0, len(it.Title))
+
+	var bodyTerms []*QueryNode // title: terms don't mark the description
+	for _, t := range terms {
+		if t.Field == "" {
+			bodyTerms = append(bodyTerms, t)
+		}
+	}
+	desc := stripHTML(it.Description)
+	words, spans := markSpans(desc, bodyTerms)
+	if len(words) == 0 {
+		return h
+	}
+
+	first := 0
+	if len(spans) > 0 {
+		for i, w := range words {
+			if w[0] == spans[0].start {
+				first = i
+				break
+			}
+		}
+	}
+	lo := first - snippetWords/3
+	if lo < 0 {
+		lo = 0
+	}
+	hi := lo + snippetWords
+	if hi > len(words) {
+		hi = len(words)
+	}
+
+	from, to := words[lo][0], words[hi-1][1]
+	if lo == 0 {
+		from = 0
+	}
+	if hi == len(words) {
+		to = len(desc)
+	}
+	h.Snippet = renderMarked(desc, spans, from, to)
+	if lo > 0 {
+		h.Snippet = "…" + h.Snippet
+	}
+	if hi < len(words) {
+		h.Snippet += "…"
+	}
+	return h
+}
@@ -0,0 +1,85 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+// terms parses query and returns the terms highlightItem marks.
+func terms(t *testing.T, query string) []*QueryNode {
+	t.Helper()
+	ast, err := ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ast.positiveTextNodes()
+}
+
+// unmark drops the <mark> tags highlighting added.
+func unmark(s string) string {
+	return strings.NewReplacer("<mark>", "", "</mark>", "").Replace(s)
+}
+
+func TestHighlightItemEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    FeedItem
+		title   string
+		snippet string
+	}{
+		{
+			"script in title",
+			FeedItem{Title: `<script>alert("drone")</script> drone strike`},
+			`&lt;script&gt;alert(&#34;<mark>drone</mark>&#34;)&lt;/script&gt; <mark>drone</mark> strike`,
+			"",
+		},
+		{
+			"ampersand in title",
+			FeedItem{Title: "AT&T wins drone contract"},
+			"AT&amp;T wins <mark>drone</mark> contract",
+			"",
+		},
+		{
+			"tags stripped, entities re-escaped in description",
+			FeedItem{Title: "Update", Description: `<p>New <b>drone</b> rules &lt;script&gt;x&lt;/script&gt; &amp; more</p>`},
+			"Update",
+			"New <mark>drone</mark> rules &lt;script&gt;x&lt;/script&gt; &amp; more",
+		},
+		{
+			"mark inside the text is escaped too",
+			FeedItem{Title: "<mark>drone</mark>"},
+			"&lt;mark&gt;<mark>drone</mark>&lt;/mark&gt;",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		h := highlightItem(&tt.item, terms(t, "drone"))
+		if h.Title != tt.title {
+			t.Errorf("%s: title = %s, want %s", tt.name, h.Title, tt.title)
+		}
+		if h.Snippet != tt.snippet {
+			t.Errorf("%s: snippet = %s, want %s", tt.name, h.Snippet, tt.snippet)
+		}
+		for _, out := range []string{h.Title, h.Snippet} {
+			if strings.ContainsAny(unmark(out), "<>") {
+				t.Errorf("%s: %s has markup besides <mark>", tt.name, out)
+			}
+		}
+	}
+}
+
+func TestHighlightItemMarksStemsAndPhrases(t *testing.T) {
+	it := FeedItem{
+		Title:       "Army contracting for hypersonic glide vehicles",
+		Description: "The glide vehicle program signed new contracts.",
+	}
+	h := highlightItem(&it, terms(t, `contracts "glide vehicle" title:army`))
+	// adjacent marked words share one <mark>
+	if want := "<mark>Army contracting</mark> for hypersonic <mark>glide vehicles</mark>"; h.Title != want {
+		t.Errorf("title = %s, want %s", h.Title, want)
+	}
+	// title: terms don't mark the description
+	if want := "The <mark>glide vehicle</mark> program signed new <mark>contracts</mark>."; h.Snippet != want {
+		t.Errorf("snippet = %s, want %s", h.Snippet, want)
+	}
+}
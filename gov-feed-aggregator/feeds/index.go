@@ -0,0 +1,511 @@
+package feeds
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── BM25 INVERTED INDEX ───────────────────── */
+
+// The index is kept up to date by the ingester and serves the "memory"
+// search backend. It is snapshotted to disk after ingest passes so a
+// restart doesn't have to rebuild it from Postgres.
+
+const (
+	bm25K1     = 1.2
+	bm25B      = 0.75
+	titleBoost = 2.0
+)
+
+// indexDoc is one article. Title/Desc/Content are its analyzed token
+// streams (stopwords kept, for phrase matching); TitleN/BodyN count the
+// non-stopword tokens that went into the postings.
+type indexDoc struct {
+	Item     FeedItem
+	SourceID int
+	Host     string
+	Title    []string
+	Desc     []string
+	Content  []string
+	TitleN   int
+	BodyN    int
+	Deleted  bool
+}
+
+type invertedIndex struct {
+	mu sync.RWMutex
+
+	Docs    []*indexDoc
+	ByLink  map[string]int         // link → doc id
+	Title   map[string]map[int]int // stem → doc id → tf
+	Body    map[string]map[int]int // description + content
+	Surface map[string]string      // stem → a word it came from, for suggestions
+	TitleN  int                    // total TitleN of live docs
+	BodyN   int                    // total BodyN of live docs
+	Live    int
+
+	// Analyzer is the analyzerVersion the postings were built with; a
+	// snapshot from another version is rebuilt rather than loaded.
+	Analyzer int
+
+	gen      uint64 // bumped on every change, so derived structures know to rebuild
+	savedGen uint64 // gen of the last snapshot on disk; dirty while they differ
+}
+
+var searchIndex = newInvertedIndex()
+
+// analyzerVersion changes whenever analyze would produce different stems.
+const analyzerVersion = 2
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{
+		ByLink:   make(map[string]int),
+		Title:    make(map[string]map[int]int),
+		Body:     make(map[string]map[int]int),
+		Surface:  make(map[string]string),
+		Analyzer: analyzerVersion,
+	}
+}
+
+// analyzeLocked is analyze, also remembering a surface form per stem.
+func (idx *invertedIndex) analyzeLocked(s string) []string {
+	toks := tokenize(s)
+	for i, t := range toks {
+		toks[i] = stem(t)
+		if _, ok := idx.Surface[toks[i]]; !ok {
+			idx.Surface[toks[i]] = t
+		}
+	}
+	return toks
+}
+
+// add indexes (or re-indexes) the items of one source.
+func (idx *invertedIndex) add(sourceID int, items []FeedItem) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, it := range items {
+		if it.Link == "" {
+			continue
+		}
+		doc := &indexDoc{
+			Item:     it,
+			SourceID: sourceID,
+			Title:    idx.analyzeLocked(it.Title),
+			Desc:     idx.analyzeLocked(stripHTML(it.Description)),
+			Content:  idx.analyzeLocked(it.Content),
+		}
+		doc.Item.MatchedFields = nil
+		doc.Item.Highlights = nil
+		if u, err := url.Parse(it.Link); err == nil {
+			doc.Host = strings.ToLower(u.Host)
+		}
+
+		id, exists := idx.ByLink[it.Link]
+		if exists {
+			idx.unpostLocked(id)
+			idx.Docs[id] = doc
+		} else {
+			id = len(idx.Docs)
+			idx.Docs = append(idx.Docs, doc)
+			idx.ByLink[it.Link] = id
+		}
+		idx.postLocked(id)
+	}
+	idx.gen++
+}
+
+func (idx *invertedIndex) postLocked(id int) {
+	doc := idx.Docs[id]
+	for _, t := range doc.Title {
+		if stopwords[t] {
+			continue
+		}
+		bumpPosting(idx.Title, t, id, 1)
+		doc.TitleN++
+	}
+	for _, field := range [][]string{doc.Desc, doc.Content} {
+		for _, t := range field {
+			if stopwords[t] {
+				continue
+			}
+			bumpPosting(idx.Body, t, id, 1)
+			doc.BodyN++
+		}
+	}
+	idx.TitleN += doc.TitleN
+	idx.BodyN += doc.BodyN
+	idx.Live++
+}
+
+func (idx *invertedIndex) unpostLocked(id int) {
+	doc := idx.Docs[id]
+	if doc.Deleted {
+		return
+	}
+	for _, t := range doc.Title {
+		if !stopwords[t] {
+			bumpPosting(idx.Title, t, id, -1)
+		}
+	}
+	for _, field := range [][]string{doc.Desc, doc.Content} {
+		for _, t := range field {
+			if !stopwords[t] {
+				bumpPosting(idx.Body, t, id, -1)
+			}
+		}
+	}
+	idx.TitleN -= doc.TitleN
+	idx.BodyN -= doc.BodyN
+	idx.Live--
+	doc.Deleted = true
+}
+
+func bumpPosting(postings map[string]map[int]int, term string, id, delta int) {
+	p := postings[term]
+	if p == nil {
+		p = make(map[int]int)
+		postings[term] = p
+	}
+	if p[id] += delta; p[id] <= 0 {
+		delete(p, id)
+		if len(p) == 0 {
+			delete(postings, term)
+		}
+	}
+}
+
+// bm25 scores one term in one field of one doc.
+func bm25(tf, docLen int, avgLen float64, df, n int) float64 {
+	if tf == 0 || df == 0 {
+		return 0
+	}
+	idf := math.Log(1 + (float64(n-df)+0.5)/(float64(df)+0.5))
+	norm := 1 - bm25B + bm25B*float64(docLen)/math.Max(avgLen, 1)
+	return idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+}
+
+// search evaluates ast over the live docs of the given sources and ranks
+// the matches by BM25 (title weighted above body) plus a recency bump.
+func (idx *invertedIndex) search(ast *QueryNode, sourceIDs map[int]bool, deep bool) []FeedItem {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := ast.positiveTextNodes()
+	fuzzy := ast.hasFuzzy()
+	var stems []string // every stem a term or expansion can hit
+	seen := map[string]bool{}
+	addStems := func(seq []string) {
+		for _, s := range seq {
+			if !stopwords[s] && !seen[s] {
+				seen[s] = true
+				stems = append(stems, s)
+			}
+		}
+	}
+	for _, t := range terms {
+		addStems(t.stems)
+		for _, a := range t.alts {
+			addStems(a.stems)
+		}
+	}
+
+	/* candidates: postings of the query stems, or every doc if the query
+	   can match without any text (filters only, pure exclusions, …) */
+	var candidates []int
+	if ast.needsText() && len(stems) > 0 {
+		set := map[int]bool{}
+		for _, s := range stems {
+			for id := range idx.Title[s] {
+				set[id] = true
+			}
+			if deep {
+				for id := range idx.Body[s] {
+					set[id] = true
+				}
+			}
+		}
+		for id := range set {
+			candidates = append(candidates, id)
+		}
+	} else {
+		candidates = make([]int, len(idx.Docs))
+		for i := range idx.Docs {
+			candidates[i] = i
+		}
+	}
+
+	avgTitle := float64(idx.TitleN) / math.Max(float64(idx.Live), 1)
+	avgBody := float64(idx.BodyN) / math.Max(float64(idx.Live), 1)
+
+	var out []FeedItem
+
+	for _, id := range candidates {
+		doc := idx.Docs[id]
+		if doc.Deleted || !sourceIDs[doc.SourceID] {
+			continue
+		}
+		d := &matchDoc{item: &doc.Item, title: doc.Title, desc: doc.Desc,
+			content: doc.Content, host: doc.Host, deep: deep}
+		if !ast.Match(d) {
+			continue
+		}
+
+		stemScore := func(s string) float64 {
+			sc := titleBoost * bm25(idx.Title[s][id], doc.TitleN, avgTitle, len(idx.Title[s]), idx.Live)
+			if deep {
+				sc += bm25(idx.Body[s][id], doc.BodyN, avgBody, len(idx.Body[s]), idx.Live)
+			}
+			return sc
+		}
+		seqScore := func(seq []string) (sum float64, n int) {
+			for _, s := range seq {
+				if !stopwords[s] {
+					sum += stemScore(s)
+					n++
+				}
+			}
+			return sum, n
+		}
+
+		/* each term scores its literal hit, or its best expansion at that
+		   expansion's weight (per word, so long expansions don't win) */
+		score := 0.0
+		for _, t := range terms {
+			best, n := seqScore(t.stems)
+			for _, a := range t.alts {
+				if sum, an := seqScore(a.stems); an > 0 {
+					if v := a.weight * sum / float64(an) * float64(max(n, 1)); v > best {
+						best = v
+					}
+				}
+			}
+			score += best
+		}
+		if !doc.Item.Published.IsZero() {
+			if hrs := time.Since(doc.Item.Published).Hours(); hrs <= 24 {
+				score += 0.5
+			} else if hrs <= 72 {
+				score += 0.2
+			}
+		}
+
+		item := doc.Item
+		item.Score = score
+		if fuzzy {
+			literal := *d
+			literal.noFuzzy = true
+			item.fuzzyOnly = !ast.Match(&literal)
+		}
+		item.MatchedFields = d.matchedFields(terms)
+		if len(terms) > 0 {
+			item.Highlights = highlightItem(&item, terms)
+		}
+		out = append(out, item)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Published.After(out[j].Published)
+	})
+	return out
+}
+
+/* ───────────────── SNAPSHOTS ─────────────────────────────── */
+
+// InitIndex restores the index from its snapshot, or rebuilds it from the
+// article store when there is none. A restored snapshot is reconciled
+// against the store: articles committed after it was written (the ingester
+// saves rows and validators before the next snapshot, so a crash in between
+// would otherwise leave them unsearchable behind 304s) are indexed on top.
+func InitIndex() error {
+	if err := searchIndex.load(indexSnapshotPath); err == nil {
+		log.Printf("📚 Search index restored from %s (%d docs)", indexSnapshotPath, searchIndex.Live)
+		return reconcileIndex()
+	} else if !os.IsNotExist(err) {
+		log.Printf("⚠️ Ignoring index snapshot %s: %v", indexSnapshotPath, err)
+	}
+
+	if err := indexArticles(`WHERE source_id IS NOT NULL`); err != nil {
+		return fmt.Errorf("rebuild index: %w", err)
+	}
+	log.Printf("📚 Search index rebuilt from DB (%d docs)", searchIndex.Live)
+	return searchIndex.save(indexSnapshotPath)
+}
+
+// reconcileIndex indexes stored articles the snapshot doesn't know about.
+func reconcileIndex() error {
+	rows, err := auth.DB.Query(`SELECT link FROM articles WHERE source_id IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("reconcile index: %w", err)
+	}
+	var stored []string
+	for rows.Next() {
+		var link string
+		if err := rows.Scan(&link); err != nil {
+			rows.Close()
+			return err
+		}
+		stored = append(stored, link)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	missing := searchIndex.missingLinks(stored)
+	if len(missing) == 0 {
+		return nil
+	}
+	if err := indexArticles(`WHERE source_id IS NOT NULL AND link = ANY($1)`, pq.Array(missing)); err != nil {
+		return fmt.Errorf("reconcile index: %w", err)
+	}
+	log.Printf("📚 Indexed %d articles missing from the snapshot", len(missing))
+	return searchIndex.save(indexSnapshotPath)
+}
+
+// missingLinks returns the links that have no document in the index.
+func (idx *invertedIndex) missingLinks(links []string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var out []string
+	for _, l := range links {
+		if _, ok := idx.ByLink[l]; !ok {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// indexArticles adds the stored articles selected by where to the index.
+func indexArticles(where string, args ...interface{}) error {
+	rows, err := auth.DB.Query(`
+		SELECT link, title, description, content, published, category, source, source_id
+		FROM articles
+		`+where, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bySource := make(map[int][]FeedItem)
+	for rows.Next() {
+		var it FeedItem
+		var published sql.NullTime
+		var sourceID int
+		if err := rows.Scan(&it.Link, &it.Title, &it.Description, &it.Content, &published,
+			&it.Category, &it.Source, &sourceID); err != nil {
+			return err
+		}
+		it.Published = published.Time
+		bySource[sourceID] = append(bySource[sourceID], it)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, items := range bySource {
+		searchIndex.add(id, items)
+	}
+	return nil
+}
+
+// saveIfDirty snapshots the index if anything was added since last time.
+func (idx *invertedIndex) saveIfDirty(path string) {
+	idx.mu.RLock()
+	dirty := idx.gen != idx.savedGen
+	idx.mu.RUnlock()
+	if !dirty {
+		return
+	}
+	if err := idx.save(path); err != nil {
+		log.Printf("⚠️ Could not snapshot search index: %v", err)
+	}
+}
+
+func (idx *invertedIndex) save(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	/* encode to memory under the read lock, so searches carry on; the
+	   disk write happens after it is released */
+	var buf bytes.Buffer
+	idx.mu.RLock()
+	gen := idx.gen
+	err := gob.NewEncoder(&buf).Encode(idx)
+	idx.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*.gob")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = buf.WriteTo(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.savedGen = max(idx.savedGen, gen)
+	idx.mu.Unlock()
+	return nil
+}
+
+func (idx *invertedIndex) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	loaded := newInvertedIndex()
+	loaded.Analyzer = 0 // gob skips zero fields: a snapshot without one stays 0
+	if err := gob.NewDecoder(f).Decode(loaded); err != nil {
+		return err
+	}
+	if loaded.Analyzer != analyzerVersion {
+		return fmt.Errorf("built by analyzer v%d, want v%d", loaded.Analyzer, analyzerVersion)
+	}
+
+	idx.mu.Lock()
+	idx.Docs, idx.ByLink = loaded.Docs, loaded.ByLink
+	idx.Title, idx.Body = loaded.Title, loaded.Body
+	if idx.Surface = loaded.Surface; idx.Surface == nil {
+		idx.Surface = make(map[string]string) // snapshot predates suggestions
+	}
+	idx.TitleN, idx.BodyN, idx.Live = loaded.TitleN, loaded.BodyN, loaded.Live
+	idx.gen++
+	idx.savedGen = idx.gen
+	idx.mu.Unlock()
+	return nil
+}
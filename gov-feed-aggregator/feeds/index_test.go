@@ -0,0 +1,89 @@
+package feeds
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexSnapshotRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	idx := newInvertedIndex()
+	idx.add(1, []FeedItem{{Link: "https://example.mil/a", Title: "Hypersonic glide vehicle test"}})
+
+	if err := idx.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if idx.gen != idx.savedGen {
+		t.Errorf("index still dirty after save (gen %d, saved %d)", idx.gen, idx.savedGen)
+	}
+
+	idx.add(1, []FeedItem{{Link: "https://example.mil/b", Title: "Scramjet engine"}})
+	if idx.gen == idx.savedGen {
+		t.Errorf("index not dirty after add")
+	}
+
+	loaded := newInvertedIndex()
+	if err := loaded.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Live != 1 || len(loaded.Title["hypersonic"]) != 1 {
+		t.Errorf("loaded %d live docs, postings %v; want the one saved doc", loaded.Live, loaded.Title["hypersonic"])
+	}
+	if loaded.gen != loaded.savedGen {
+		t.Errorf("freshly loaded index is dirty")
+	}
+}
+
+func TestIndexSearchDoesNotConflateStems(t *testing.T) {
+	idx := newInvertedIndex()
+	idx.add(1, []FeedItem{
+		{Link: "https://example.mil/a", Title: "New defense budget unveiled"},
+		{Link: "https://example.mil/b", Title: "Reply to Congress on drones"},
+		{Link: "https://example.mil/c", Title: "Defense news roundup"},
+	})
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"defense news", []string{"https://example.mil/c"}},
+		{"rep", nil},
+		{"reply", []string{"https://example.mil/b"}},
+	}
+	for _, tt := range tests {
+		ast, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := links(idx.search(ast, map[int]bool{1: true}, false))
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIndexSnapshotFromOtherAnalyzer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	idx := newInvertedIndex()
+	idx.Analyzer = analyzerVersion - 1
+	idx.add(1, []FeedItem{{Link: "https://example.mil/a", Title: "Defense news"}})
+	if err := idx.save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := newInvertedIndex().load(path); err == nil {
+		t.Errorf("loaded a snapshot built by another analyzer version")
+	}
+}
+
+func TestIndexMissingLinks(t *testing.T) {
+	idx := newInvertedIndex()
+	idx.add(1, []FeedItem{{Link: "https://example.mil/a", Title: "Indexed before the snapshot"}})
+
+	stored := []string{"https://example.mil/a", "https://example.mil/b", "https://example.mil/c"}
+	if got := fmt.Sprint(idx.missingLinks(stored)); got != "[https://example.mil/b https://example.mil/c]" {
+		t.Errorf("missingLinks = %s, want the two unindexed articles", got)
+	}
+	if got := idx.missingLinks(stored[:1]); len(got) != 0 {
+		t.Errorf("missingLinks = %v for an up-to-date index", got)
+	}
+}
@@ -0,0 +1,216 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+/* ───────────────── BACKGROUND INGESTER ───────────────────── */
+
+const ingestTick = 30 * time.Second
+
+var (
+	lastPolled   = make(map[int]time.Time) // source id → last attempt
+	lastPolledMu sync.Mutex
+)
+
+// StartIngester polls every enabled source on its own interval and upserts
+// the results into `articles` until ctx is cancelled. Run it in a goroutine.
+func StartIngester(ctx context.Context) {
+	log.Printf("🛰️ Ingester started (tick %v, %d workers)", ingestTick, fetchWorkers)
+
+	ticker := time.NewTicker(ingestTick)
+	defer ticker.Stop()
+
+	for {
+		ingestDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("🛑 Ingester stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ingestDue refreshes every source whose poll interval has elapsed.
+func ingestDue(ctx context.Context) {
+	now := time.Now()
+	var due []Source
+
+	lastPolledMu.Lock()
+	for _, src := range enabledSources() {
+		interval := time.Duration(src.PollInterval) * time.Second
+		if now.Sub(lastPolled[src.ID]) >= interval {
+			lastPolled[src.ID] = now
+			due = append(due, src)
+		}
+	}
+	lastPolledMu.Unlock()
+
+	forEachSource(ctx, due, func(ctx context.Context, src Source) {
+		if _, err := refreshSource(ctx, src); err != nil && !errors.Is(err, errCircuitOpen) {
+			fmt.Printf("❌ ingest failed: %s: %v\n", src.URL, err)
+		}
+	})
+	searchIndex.saveIfDirty(indexSnapshotPath)
+	RebuildSuggestions(ctx)
+}
+
+// storeStatus reports how current the stored articles of srcs are.
+// Searches never fetch; they read the store the ingester keeps filling. A
+// source the ingester wrote within its poll interval counts as a cache
+// hit, one whose last fetch failed as failed, and one it hasn't reached
+// yet (e.g. right after boot) as pending.
+func storeStatus(srcs []Source) SearchMeta {
+	meta := SearchMeta{SourcesQueried: len(srcs)}
+	now := time.Now()
+
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	for _, src := range srcs {
+		if isFresh(src) {
+			meta.CacheHits++
+			continue
+		}
+		h, ok := health[src.ID]
+		switch {
+		case ok && h.ConsecutiveFailures > 0:
+			reason := h.LastError
+			if state, _ := breakerState(h, now); state == breakerOpen {
+				reason = "circuit open"
+			}
+			meta.SourcesFailed = append(meta.SourcesFailed,
+				SourceFailure{Source: src.Name, URL: src.URL, Reason: reason})
+		default:
+			meta.SourcesPending = append(meta.SourcesPending, src.Name)
+		}
+	}
+	sort.Strings(meta.SourcesPending)
+	meta.Partial = len(meta.SourcesFailed) > 0 || len(meta.SourcesPending) > 0
+	return meta
+}
+
+// forEachSource runs fn over srcs on a pool of fetchWorkers goroutines and
+// waits for them. Sources not yet started when ctx ends are dropped.
+func forEachSource(ctx context.Context, srcs []Source, fn func(context.Context, Source)) {
+	if len(srcs) == 0 {
+		return
+	}
+
+	jobs := make(chan Source)
+	var wg sync.WaitGroup
+
+	workers := fetchWorkers
+	if workers > len(srcs) {
+		workers = len(srcs)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for src := range jobs {
+				fn(ctx, src)
+			}
+		}()
+	}
+
+feed:
+	for _, src := range srcs {
+		select {
+		case jobs <- src:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func isFresh(src Source) bool {
+	ttl := time.Duration(src.PollInterval)*time.Second + cacheTTL
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	return time.Since(sourceCacheTimes[src.URL]) < ttl
+}
+
+// refreshSource downloads one feed, writes it to the article store and
+// records the outcome in the source's health. Sources whose breaker is
+// open are skipped with errCircuitOpen. Each fetch gets sourceTimeout on
+// top of ctx; if ctx itself ends first the attempt isn't held against the
+// source. notModified reports a 304.
+func refreshSource(ctx context.Context, src Source) (notModified bool, err error) {
+	if !allowFetch(src) {
+		return false, errCircuitOpen
+	}
+
+	start := time.Now()
+	itemCount := -1
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil && ctx.Err() != nil {
+			releaseProbe(src)
+			return
+		}
+		recordFetch(src, time.Since(start), itemCount, err)
+	}()
+
+	fetchCtx, cancel := context.WithTimeout(ctx, sourceTimeout)
+	defer cancel()
+
+	feed, next, notModified, err := fetchFeed(fetchCtx, src)
+	if err != nil {
+		return false, err
+	}
+
+	/* 304 → the stored copy is still current, just bump freshness */
+	if !notModified {
+		items := toFeedItems(feed.Items, src)
+		if err := upsertArticles(ctx, src, items); err != nil {
+			return false, err
+		}
+		searchIndex.add(src.ID, items)
+		itemCount = len(items)
+		if next != nil {
+			saveValidators(src, *next) // only now that the items are stored
+		}
+	}
+
+	cacheMu.Lock()
+	sourceCacheTimes[src.URL] = time.Now()
+	cacheMu.Unlock()
+	return notModified, nil
+}
+
+func toFeedItems(raw []*gofeed.Item, src Source) []FeedItem {
+	items := make([]FeedItem, 0, len(raw))
+	for _, it := range raw {
+		var published time.Time
+		if it.PublishedParsed != nil {
+			published = *it.PublishedParsed
+		} else if it.UpdatedParsed != nil {
+			published = *it.UpdatedParsed
+		}
+		items = append(items, FeedItem{
+			Title:       it.Title,
+			Link:        it.Link,
+			Description: it.Description,
+			Content:     stripHTML(it.Content),
+			Published:   published,
+			Category:    classifyItem(it, src),
+			Source:      src.Name,
+		})
+	}
+	return items
+}
@@ -0,0 +1,255 @@
+package feeds
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* ───────────────── PAGINATION ────────────────────────────── */
+
+// Pages are keyset-based: a cursor is the sort key of the last item served,
+// and the next page is everything that sorts strictly after it. Items
+// ingested between requests land wherever they sort, so date and source
+// scrolling never repeats or skips an item the way an offset would.
+//
+// Relevance can't be keyed that way: scores drift between requests (BM25
+// statistics move as articles arrive, the recency bump follows the clock,
+// topic scores decay and change with every reaction). So the first page
+// freezes the order it was served in as a ranking, kept in memory for
+// rankingTTL after its last use, and relevance cursors are positions in it.
+// Items a later request finds that the ranking doesn't know yet are
+// appended to it in score order, so they come after everything already
+// ranked. A cursor whose ranking expired falls back to keying on score.
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+	rankingTTL      = 30 * time.Minute
+	maxRankings     = 500
+)
+
+// PageOptions is a parsed limit / cursor / sort triple.
+type PageOptions struct {
+	Limit int
+	Sort  string // relevance | date | source
+	after *pageCursor
+}
+
+// pageCursor is the position of an item in a given sort order. Link breaks
+// every tie, so the order is total. Relevance cursors also carry the
+// ranking they belong to and the item's place in it.
+type pageCursor struct {
+	Sort      string    `json:"s"`
+	Score     float64   `json:"r,omitempty"`
+	Published time.Time `json:"p"`
+	Source    string    `json:"o,omitempty"`
+	Link      string    `json:"l"`
+	Ranking   string    `json:"k,omitempty"`
+	Pos       int       `json:"n,omitempty"`
+}
+
+// ParsePageOptions validates the raw query parameters; empty values get
+// the defaults (50 items, relevance order).
+func ParsePageOptions(limit, cursor, sortBy string) (PageOptions, error) {
+	p := PageOptions{Limit: defaultPageSize, Sort: "relevance"}
+
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageSize {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		p.Limit = n
+	}
+
+	switch sortBy {
+	case "":
+	case "relevance", "date", "source":
+		p.Sort = sortBy
+	default:
+		return p, fmt.Errorf("sort must be relevance, date or source")
+	}
+
+	if cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		var c pageCursor
+		if err == nil {
+			err = json.Unmarshal(raw, &c)
+		}
+		if err != nil || c.Link == "" {
+			return p, fmt.Errorf("invalid cursor")
+		}
+		if c.Sort != p.Sort {
+			return p, fmt.Errorf("cursor was issued for sort=%s", c.Sort)
+		}
+		p.after = &c
+	}
+	return p, nil
+}
+
+func cursorOf(it *FeedItem, sortBy string) pageCursor {
+	c := pageCursor{Sort: sortBy, Published: it.Published, Link: it.Link}
+	switch sortBy {
+	case "relevance":
+		c.Score = it.Score
+	case "source":
+		c.Source = strings.ToLower(it.Source)
+	}
+	return c
+}
+
+// before reports whether a sorts ahead of b.
+func (a pageCursor) before(b pageCursor) bool {
+	switch a.Sort {
+	case "relevance":
+		if a.Ranking != "" && a.Ranking == b.Ranking {
+			if a.Pos != b.Pos {
+				return a.Pos < b.Pos
+			}
+		} else if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+	case "source":
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+	}
+	if !a.Published.Equal(b.Published) {
+		return a.Published.After(b.Published)
+	}
+	return a.Link < b.Link
+}
+
+func (c pageCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Paginate counts facets over the full r.Items, then sorts them, keeps the
+// page after p's cursor and sets NextCursor if anything is left over.
+func (r *SearchResult) Paginate(p PageOptions) {
+	r.Facets = computeFacets(r.Items, time.Now())
+
+	keys := make([]pageCursor, len(r.Items))
+	for i := range r.Items {
+		keys[i] = cursorOf(&r.Items[i], p.Sort)
+	}
+	idx := make([]int, len(r.Items))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return keys[idx[i]].before(keys[idx[j]]) })
+
+	var rk *ranking
+	if p.Sort == "relevance" {
+		if rk = rankingFor(p.after); rk != nil {
+			rk.place(r.Items, idx, keys)
+			sort.Slice(idx, func(i, j int) bool { return keys[idx[i]].before(keys[idx[j]]) })
+		}
+	}
+
+	page := []FeedItem{}
+	var last pageCursor
+	r.NextCursor = ""
+	for _, i := range idx {
+		if p.after != nil && !p.after.before(keys[i]) {
+			continue
+		}
+		if len(page) == p.Limit {
+			if rk != nil {
+				rk.keep()
+			}
+			r.NextCursor = last.encode()
+			break
+		}
+		page = append(page, r.Items[i])
+		last = keys[i]
+	}
+	r.Items = page
+	if r.highlight != nil {
+		r.highlight(r.Items)
+	}
+}
+
+/* ── frozen relevance order ── */
+
+// ranking is the frozen relevance order of one listing: links in the
+// order they were first ranked.
+type ranking struct {
+	id   string
+	mu   sync.Mutex
+	pos  map[string]int
+	used time.Time
+}
+
+var (
+	rankings   = make(map[string]*ranking)
+	rankingsMu sync.Mutex
+)
+
+// rankingFor returns the ranking after continues, or a new (not yet kept)
+// one for a first page. It is nil when after's ranking has expired.
+func rankingFor(after *pageCursor) *ranking {
+	if after == nil {
+		b := make([]byte, 8)
+		rand.Read(b)
+		return &ranking{id: hex.EncodeToString(b), pos: make(map[string]int)}
+	}
+	if after.Ranking == "" {
+		return nil
+	}
+	rankingsMu.Lock()
+	defer rankingsMu.Unlock()
+	rk := rankings[after.Ranking]
+	if rk == nil || time.Since(rk.used) > rankingTTL {
+		return nil
+	}
+	rk.used = time.Now()
+	return rk
+}
+
+// place sets each item's Pos in the ranking, appending the ones it hasn't
+// seen in their score order (idx).
+func (rk *ranking) place(items []FeedItem, idx []int, keys []pageCursor) {
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+	for _, i := range idx {
+		n, ok := rk.pos[items[i].Link]
+		if !ok {
+			n = len(rk.pos) + 1
+			rk.pos[items[i].Link] = n
+		}
+		keys[i].Ranking, keys[i].Pos = rk.id, n
+	}
+}
+
+// keep registers rk so later pages can find it, dropping expired rankings
+// and, past maxRankings, the least recently used.
+func (rk *ranking) keep() {
+	rankingsMu.Lock()
+	defer rankingsMu.Unlock()
+
+	rk.used = time.Now()
+	if _, ok := rankings[rk.id]; ok {
+		return
+	}
+	var oldest *ranking
+	for id, other := range rankings {
+		if time.Since(other.used) > rankingTTL {
+			delete(rankings, id)
+		} else if oldest == nil || other.used.Before(oldest.used) {
+			oldest = other
+		}
+	}
+	if oldest != nil && len(rankings) >= maxRankings {
+		delete(rankings, oldest.id)
+	}
+	rankings[rk.id] = rk
+}
@@ -0,0 +1,69 @@
+package feeds
+
+import (
+	"fmt"
+	"testing"
+)
+
+func scored(scores map[string]float64) []FeedItem {
+	var items []FeedItem
+	for link, s := range scores {
+		items = append(items, FeedItem{Link: link, Title: link, Score: s})
+	}
+	return items
+}
+
+func links(items []FeedItem) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		out[i] = it.Link
+	}
+	return out
+}
+
+func nextPage(t *testing.T, items []FeedItem, cursor string, limit int) *SearchResult {
+	t.Helper()
+	p, err := ParsePageOptions(fmt.Sprint(limit), cursor, "relevance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &SearchResult{Items: items}
+	r.Paginate(p)
+	return r
+}
+
+func TestRelevancePagingSurvivesScoreDrift(t *testing.T) {
+	first := nextPage(t, scored(map[string]float64{"a": 5, "b": 4, "c": 3, "d": 2, "e": 1}), "", 2)
+	if got := fmt.Sprint(links(first.Items)); got != "[a b]" {
+		t.Fatalf("page 1 = %s, want [a b]", got)
+	}
+
+	// between requests every score moves, and a new top article arrives
+	drifted := scored(map[string]float64{"a": 1, "b": 2, "c": 3.5, "d": 9, "e": 0.5, "new": 10})
+	second := nextPage(t, drifted, first.NextCursor, 2)
+	if got := fmt.Sprint(links(second.Items)); got != "[c d]" {
+		t.Fatalf("page 2 = %s, want the frozen order [c d]", got)
+	}
+	third := nextPage(t, drifted, second.NextCursor, 2)
+	if got := fmt.Sprint(links(third.Items)); got != "[e new]" {
+		t.Fatalf("page 3 = %s, want [e new]", got)
+	}
+	if third.NextCursor != "" {
+		t.Errorf("page 3 has a next cursor after the last item")
+	}
+}
+
+func TestRelevancePagingExpiredRanking(t *testing.T) {
+	first := nextPage(t, scored(map[string]float64{"a": 5, "b": 4, "c": 3}), "", 1)
+
+	p, _ := ParsePageOptions("1", first.NextCursor, "relevance")
+	rankingsMu.Lock()
+	delete(rankings, p.after.Ranking)
+	rankingsMu.Unlock()
+
+	// falls back to keying on the score the cursor recorded
+	second := nextPage(t, scored(map[string]float64{"a": 5, "b": 4, "c": 3}), first.NextCursor, 1)
+	if got := fmt.Sprint(links(second.Items)); got != "[b]" {
+		t.Errorf("page 2 = %s, want [b]", got)
+	}
+}
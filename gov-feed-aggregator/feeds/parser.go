@@ -1,18 +1,13 @@
 package feeds
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
-	"net/url"
-	"net/http"
-	"encoding/json"
-	"sort"
-	"regexp"
 
 	"github.com/mmcdole/gofeed"
-	"gov-feed-aggregator/auth"
 )
 
 type FeedItem struct {
@@ -21,84 +16,30 @@ type FeedItem struct {
 	Description string    `json:"description"`
 	Published   time.Time `json:"published"`
 	Category    string    `json:"category"`
-}
+	Source      string    `json:"source"`
+
+	Content       string      `json:"-"`                        // plain-text body, searched in deep mode
+	Score         float64     `json:"-"`                        // relevance, for sort=relevance paging
+	MatchedFields []string    `json:"matched_fields,omitempty"` // title | description | content
+	Highlights    *Highlights `json:"highlights,omitempty"`
+
+	Personalization *Personalization `json:"personalization,omitempty"` // logged-in searches only
 
-/* ───────────────── RSS SOURCE LIST ───────────────────────── */
-
-var sources = []string{
-	// Defense news and military
-	"https://www.defenseone.com/rss/all/",
-	"https://breakingdefense.com/feed/",
-	"https://www.defensenews.com/arc/outboundfeeds/rss/?outputType=xml",
-	"https://www.realcleardefense.com/index.xml",
-	"https://www.army.mil/rss/static/85.xml",
-	"https://www.rand.org/topics/national-security.xml",
-	"https://www.af.mil/DesktopModules/ArticleCS/RSS.ashx?ContentType=1",
-	// "https://www.popularmechanics.com/rss/all.xml",
-	"https://www.defenceiq.com/rss/categories/air-forces-military-aircraft",
-	"https://www.defenceiq.com/rss/categories/armoured-vehicles",
-	"https://www.defenceiq.com/rss/categories/air-land-and-sea-defence-services",
-	"https://www.defenceiq.com/rss/categories/defence-technology",
-	"https://www.defenceiq.com/rss/categories/army-land-forces",
-	"https://www.defenceiq.com/rss/categories/naval-maritime-defence",
-	"https://www.defenceiq.com/rss/categories/cyber-defence-and-security",
-	"https://oval.mitre.org/news/rss/ovalnews.feed.xml",
-	"https://defence-blog.com/feed/",
-	"https://www.army-technology.com/feed/",
-	"https://www.airforce-technology.com/feed/",
-	"https://www.naval-technology.com/news/feed/",
-	// "https://feeds.feedburner.com/defense-technology",
-	"https://sociable.co/military-technology/feed/",
-	"https://www.defenseone.com/rss/technology/",
-	"https://defense-update.com/feed",
-	"https://breakingdefense.com/full-rss-feed/?v=2",
-
-
-	// Government Agencies
-	"https://www.defense.gov/DesktopModules/ArticleCS/RSS.ashx?ContentType=800&Site=945&max=10",
-	"https://www.defense.gov/DesktopModules/ArticleCS/RSS.ashx?max=10&ContentType=1&Site=945",
-	"https://www.defense.gov/DesktopModules/ArticleCS/RSS.ashx?ContentType=400&Site=945&max=10",
-	"https://nexus.od.nih.gov/all/feed/",
-	"https://www.ruralhealthinfo.org/rss/funding/types/grants-and-contracts.xml",
-
-	// RSS from DOS
-
-
-	// Grants
-	"https://grants.nih.gov/podcasts/All_About_Grants/AAG_Feed.xml",
-	"https://www.nsf.gov/rss/rss_www_events.xml",
-	"https://www.nsf.gov/rss/rss_www_funding_pgm_annc_inf.xml",
-	"https://www.nsf.gov/news/mmg/rss/rss_www_funding_upcoming.xml",
-	"https://www.nsf.gov/rss/rss_www_news.xml",
-	"https://www.ruralhealthinfo.org/rss/funding/types/grants-and-contracts.xml",
-
-	// Ally / international defense
-	"https://www.gov.uk/government/organisations/ministry-of-defence.atom",
-	"https://natowatch.org/news.xml",
-	"https://ukdefencejournal.org.uk/feed/",
-	// "https://bulgarianmilitary.com/feed/",
-	"https://russiandefpolicy.com/feed/",
-
-	// US‑based news
-	// "https://feeds.npr.org/1001/rss.xml",
-	// "https://rss.nytimes.com/services/xml/rss/nyt/HomePage.xml",
-	// "https://feeds.washingtonpost.com/rss/national",
-
-	// International news
-	// "https://feeds.bbci.co.uk/news/world/rss.xml",
-	// "https://www.aljazeera.com/xml/rss/all.xml",
-
-	// Tech
-	// "http://feeds.feedburner.com/TechCrunch/",
-	// "https://www.wired.com/feed/rss",
-	"https://www.nsf.gov/rss/rss_www_funding_pgm_annc_inf.xml",
+	fuzzyOnly bool // matched only through a typo correction
+}
 
+// Highlights show why an item matched: the title and a description
+// snippet with matched terms wrapped in <mark>.
+type Highlights struct {
+	Title   string `json:"title"`
+	Snippet string `json:"snippet"`
 }
 
 /* ───────────────── IN‑MEMORY CACHE ───────────────────────── */
 
+// sourceCacheTimes records when each source URL was last written to the
+// article store. A source is fresh for its poll interval plus cacheTTL.
 var (
-	sourceCache      = make(map[string][]*gofeed.Item)
 	sourceCacheTimes = make(map[string]time.Time)
 	cacheTTL         = 5 * time.Minute
 	cacheMu          sync.Mutex
@@ -106,254 +47,186 @@ var (
 
 /* ───────────────── PUBLIC WRAPPERS ───────────────────────── */
 
+// SearchOptions tunes a single search call.
+type SearchOptions struct {
+	// Filters narrow the results on top of the query; with an empty
+	// query they select from everything.
+	Filters SearchFilters
+	// UserID, when set, blends that user's topic scores into relevance.
+	UserID int
+	// Expansions are related keywords ORed into the query's plain text
+	// (see expandWith).
+	Expansions []string
+}
+
+// SearchResult is what /feed returns: the items plus how we got them.
+// Paginate fills in Facets and, when there are more items to fetch,
+// NextCursor. DidYouMean is a corrected query, set when nothing matched
+// the query as typed.
+type SearchResult struct {
+	Items      []FeedItem `json:"items"`
+	Meta       SearchMeta `json:"meta"`
+	Facets     *Facets    `json:"facets,omitempty"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	DidYouMean string     `json:"did_you_mean,omitempty"`
+
+	highlight func([]FeedItem) // marks up the served page (see Paginate)
+}
+
+// SearchMeta describes which sources contributed to a result.
+type SearchMeta struct {
+	SourcesQueried int             `json:"sources_queried"`
+	SourcesFailed  []SourceFailure `json:"sources_failed"`
+	SourcesPending []string        `json:"sources_pending"`
+	CacheHits      int             `json:"cache_hits"`
+	Partial        bool            `json:"partial"`
+	ElapsedMs      int64           `json:"elapsed_ms"`
+}
+
+type SourceFailure struct {
+	Source string `json:"source"`
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
 // QuickSearch  ➜ phase‑1 (titles only)
-func QuickSearch(query string) ([]FeedItem, error) {
-	return fetchAndFilterFeeds(query, false)
+func QuickSearch(ctx context.Context, query string, opts SearchOptions) (*SearchResult, error) {
+	return fetchAndFilterFeeds(ctx, query, false, opts)
 }
 
-// DeepSearch   ➜ phase‑2 (titles + descriptions)
-func DeepSearch(query string) ([]FeedItem, error) {
-	return fetchAndFilterFeeds(query, true)
+// DeepSearch   ➜ phase‑2 (titles + descriptions + content)
+func DeepSearch(ctx context.Context, query string, opts SearchOptions) (*SearchResult, error) {
+	return fetchAndFilterFeeds(ctx, query, true, opts)
+}
+
+// SearchContext bounds one search by deadline, or by FEED_SEARCH_TIMEOUT
+// when deadline is zero or longer than that.
+func SearchContext(ctx context.Context, deadline time.Duration) (context.Context, context.CancelFunc) {
+	if deadline <= 0 || deadline > searchTimeout {
+		deadline = searchTimeout
+	}
+	return context.WithTimeout(ctx, deadline)
 }
 
 /* ───────────────── CORE FETCH LOGIC ──────────────────────── */
 
-func fetchRelatedTerms(query string) ([]string, error) {
-    // Example using Datamuse API
-    apiURL := fmt.Sprintf("https://api.datamuse.com/words?ml=%s", url.QueryEscape(query))
-    resp, err := http.Get(apiURL)
-    if err != nil {
-        return nil, err
-    }
-    defer resp.Body.Close()
-
-    var results []struct {
-        Word string `json:"word"`
-    }
-    if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-        return nil, err
-    }
-
-    var terms []string
-    for _, result := range results {
-        terms = append(terms, result.Word)
-    }
-    return terms, nil
-}
+func fetchAndFilterFeeds(ctx context.Context, query string, deep bool, opts SearchOptions) (*SearchResult, error) {
 
-func fetchAndFilterFeeds(query string, _ bool) ([]FeedItem, error) {
+	filter := opts.Filters.node()
 
 	/* 0. Empty → everything */
-	if strings.TrimSpace(query) == "" {
-		return fetchEverythingFromSources()
+	if strings.TrimSpace(query) == "" && filter == nil {
+		return fetchEverythingFromSources(ctx, opts)
 	}
 
 	start      := time.Now()
-	fp         := gofeed.NewParser()
-	lq         := strings.ToLower(strings.TrimSpace(query))
-
-	// --- decide mode ----------------------------------------------------
-	useOR := strings.Contains(lq, ",") // ← comma means “alternatives”
 
-	// --- build the list of search terms ---------------------------------
-	lq = strings.ReplaceAll(lq, ",", " ")
-	rawTerms := strings.Fields(lq)
-
-	terms := []string{}
-	for _, t := range rawTerms {
-		if t != "" {
-			terms = append(terms, t)
+	// --- parse the query into an AST ------------------------------------
+	var ast *QueryNode
+	if strings.TrimSpace(query) == "" {
+		ast = filter
+	} else {
+		var err error
+		if ast, err = ParseQuery(query); err != nil {
+			return nil, err
+		}
+		if len(opts.Expansions) > 0 {
+			ast = expandWith(ast, opts.Expansions)
+		}
+		if filter != nil {
+			ast = &QueryNode{Op: "and", Children: []*QueryNode{ast, filter}}
 		}
-	}
-	if len(terms) == 0 {
-		fmt.Println("⚠️  no terms after normalising query")
-		return nil, nil
 	}
 
-	// pre‑compile boundary regexes
-	termRegex := make([]*regexp.Regexp, len(terms))
-	for i, t := range terms {
-		termRegex[i] = regexp.MustCompile(`\b` + regexp.QuoteMeta(t) + `\b`)
+	srcs, meta := storedCorpus()
+	searchIndex.addFuzzy(ast)
+
+	var out []FeedItem
+	var highlight func([]FeedItem)
+	var err error
+	if searchBackend == "postgres" {
+		out, highlight, err = ftsSearch(ctx, ast, srcs, deep)
+	} else {
+		ids := make(map[int]bool, len(srcs))
+		for _, s := range srcs {
+			ids[s.ID] = true
+		}
+		out = searchIndex.search(ast, ids, deep)
 	}
-	exactPhrase := regexp.MustCompile(`\b` + regexp.QuoteMeta(strings.Join(terms, " ")) + `\b`)
-
-	// ---------- rest identical to previous version ----------------------
-	type scored struct {
-		Item  FeedItem
-		Score int
+	if err != nil {
+		return nil, err
 	}
-	resCh := make(chan scored, 1000)
-	var wg sync.WaitGroup
-	seen  := map[string]bool{}
-	var mu sync.Mutex
-
-	for _, feedURL := range sources {
-		wg.Add(1)
-		go func(feedURL string) {
-			defer wg.Done()
-
-			/* cache‑aware fetch */
-			var items []*gofeed.Item
-			cacheMu.Lock()
-			if it, ok := sourceCache[feedURL]; ok && time.Since(sourceCacheTimes[feedURL]) < cacheTTL {
-				items = it
-			}
-			cacheMu.Unlock()
-
-			if items == nil {
-				feed, err := fp.ParseURL(feedURL)
-				if err != nil {
-					fmt.Printf("❌ fetch failed: %s: %v\n", feedURL, err)
-					return
-				}
-				items = feed.Items
-				cacheMu.Lock()
-				sourceCache[feedURL] = items
-				sourceCacheTimes[feedURL] = time.Now()
-				cacheMu.Unlock()
-			}
 
-			/* score / filter */
-			for _, it := range items {
-				title := strings.ToLower(it.Title)
-
-				keep := false
-				if useOR {
-					/* OR mode: keep if **any** term matches */
-					for _, re := range termRegex {
-						if re.MatchString(title) {
-							keep = true
-							break
-						}
-					}
-				} else {
-					/* AND mode: keep only if **all** terms match */
-					keep = true
-					for _, re := range termRegex {
-						if !re.MatchString(title) {
-							keep = false
-							break
-						}
-					}
-				}
-				if !keep {
-					continue
-				}
-
-				/* basic scoring */
-				score := 5                                            // baseline
-				if exactPhrase.MatchString(title) { score += 40 }     // full phrase
-				score += 10 * len(title) / 1000                       // harmless tie‑breaker
-
-				/* recency bump */
-				var pub time.Time
-				if it.PublishedParsed != nil {
-					pub = *it.PublishedParsed
-					if hrs := time.Since(pub).Hours(); hrs <= 24 {
-						score += 5
-					} else if hrs <= 72 {
-						score += 2
-					}
-				}
-
-				/* dedupe */
-				mu.Lock()
-				if seen[it.Link] { mu.Unlock(); continue }
-				seen[it.Link] = true
-				mu.Unlock()
-
-				/* async DB insert */
-				go func(l, t string) {
-					auth.DB.Exec(`INSERT INTO articles (link,title)
-					              VALUES ($1,$2) ON CONFLICT DO NOTHING`, l, t)
-				}(it.Link, it.Title)
-
-				resCh <- scored{
-					Item: FeedItem{
-						Title:       it.Title,
-						Link:        it.Link,
-						Description: it.Description,
-						Published:   pub,
-						Category:    classifyItem(it, feedURL),
-					},
-					Score: score,
-				}
-			}
-		}(feedURL)
+	if opts.UserID != 0 {
+		personalize(out, userTopics(ctx, opts.UserID))
 	}
 
-	go func() { wg.Wait(); close(resCh) }()
-
-	var all []scored
-	for s := range resCh { all = append(all, s) }
-	sort.Slice(all, func(i, j int) bool { return all[i].Score > all[j].Score })
-
-	out := make([]FeedItem, len(all))
-	for i, s := range all { out[i] = s.Item }
+	res := &SearchResult{Items: out, Meta: meta, highlight: highlight}
+	if ast.hasFuzzy() && res.LiteralMatches() == 0 {
+		res.DidYouMean = didYouMean(query, ast)
+	}
 
+	res.Meta.ElapsedMs = time.Since(start).Milliseconds()
 	fmt.Printf("✅ Search finished (%s) | %d results | %v\n",
 		query, len(out), time.Since(start))
-	return out, nil
+	return res, nil
 }
 
+// LiteralMatches counts the items that match the query as typed, not only
+// through a fuzzy correction.
+func (r *SearchResult) LiteralMatches() int {
+	n := 0
+	for _, it := range r.Items {
+		if !it.fuzzyOnly {
+			n++
+		}
+	}
+	return n
+}
 
-
-func fetchEverythingFromSources() ([]FeedItem, error) {
-	var wg sync.WaitGroup
-	fp := gofeed.NewParser()
-	resultChan := make(chan FeedItem, 1000)
-
-	for _, url := range sources {
-		wg.Add(1)
-		go func(feedURL string) {
-			defer wg.Done()
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("⚠️ Panic recovered in %s: %v\n", feedURL, r)
-				}
-			}()
-
-			feed, err := fp.ParseURL(feedURL)
-			if err != nil {
-				fmt.Printf("❌ Failed to fetch %s\n", feedURL)
-				return
-			}
-
-			for _, item := range feed.Items {
-				var published time.Time
-				if item.PublishedParsed != nil {
-					published = *item.PublishedParsed
-				}
-				resultChan <- FeedItem{
-					Title:       item.Title,
-					Link:        item.Link,
-					Description: item.Description,
-					Published:   published,
-					Category:    classifyItem(item, feedURL),
-				}
-			}
-		}(url)
+func fetchEverythingFromSources(ctx context.Context, opts SearchOptions) (*SearchResult, error) {
+	start := time.Now()
+	items, meta, err := loadCorpus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []FeedItem{}
 	}
+	meta.ElapsedMs = time.Since(start).Milliseconds()
+	return &SearchResult{Items: items, Meta: meta}, nil
+}
+
+// storedCorpus returns the enabled sources a search covers and how
+// current their stored articles are. Searches only read the store; keeping
+// it fresh is the ingester's job, so no search waits on a slow feed.
+func storedCorpus() ([]Source, SearchMeta) {
+	srcs := enabledSources()
+	return srcs, storeStatus(srcs)
+}
 
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+// loadCorpus reads every stored article of the enabled sources.
+func loadCorpus(ctx context.Context) ([]FeedItem, SearchMeta, error) {
+	srcs, meta := storedCorpus()
 
-	var items []FeedItem
-	for item := range resultChan {
-		items = append(items, item)
+	stored, err := loadStoredArticles(ctx, srcs)
+	if err != nil {
+		return nil, meta, err
 	}
-	return items, nil
+	return stored, meta, nil
 }
 
 
 /* ───────────────── CLASSIFIER ─────────────────────────────── */
 
-func classifyItem(item *gofeed.Item, url string) string {
+func classifyItem(item *gofeed.Item, src Source) string {
+	if src.CategoryHint != "" {
+		return src.CategoryHint
+	}
+
 	lowerTitle := strings.ToLower(item.Title)
 	lowerDesc := strings.ToLower(item.Description)
-	lowerURL := strings.ToLower(url)
+	lowerURL := strings.ToLower(src.URL)
 
 	domainCategories := map[string]string{
 		"defenseone":       "News",
@@ -0,0 +1,149 @@
+package feeds
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── PERSONALIZATION ───────────────────────── */
+
+// For a logged-in user, each result's relevance gets a boost from the
+// user's topic scores (learned from likes, saves and onboarding). Every
+// topic found in the title or description adds its score. The sum
+// saturates, so a heavy topic can reorder close matches but can't bury
+// what was searched for. The boost is a share of the result set's top
+// score, so PERSONALIZE_WEIGHT means the same on both backends (ts_rank
+// scores are well under 1, BM25 scores in the tens):
+//
+//	boost = PERSONALIZE_WEIGHT × top × (1 − e^(−sum / personalizeSaturation))
+
+const (
+	personalizeSaturation = 10.0
+	topicCacheTTL         = time.Minute
+)
+
+// Personalization is the boost an item got and the topics behind it.
+type Personalization struct {
+	Boost  float64  `json:"boost"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+// personalize adds each item's boost to its Score and re-sorts by it.
+func personalize(items []FeedItem, topics map[string]float64) {
+	if personalizeWeight <= 0 || len(topics) == 0 {
+		return
+	}
+	terms := topicTerms(topics)
+
+	top := 0.0
+	for _, it := range items {
+		top = max(top, it.Score)
+	}
+	if top <= 0 {
+		top = 1 // all filters, nothing scored: the boost alone orders
+	}
+
+	for i := range items {
+		it := &items[i]
+		sum, names := matchTopics(terms, it)
+		boost := math.Round(personalizeWeight*top*topicAffinity(sum)*1000) / 1000
+		it.Score += boost
+		it.Personalization = &Personalization{Boost: boost, Topics: names}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return items[i].Published.After(items[j].Published)
+	})
+}
+
+type topicTerm struct {
+	name  string
+	stems []string
+	score float64
+}
+
+// topicTerms analyzes a user's topics for matching, in name order.
+// Topics made only of stopwords are dropped.
+func topicTerms(topics map[string]float64) []topicTerm {
+	var terms []topicTerm
+	for name, score := range topics {
+		stems := analyze(name)
+		for _, st := range stems {
+			if !stopwords[st] {
+				terms = append(terms, topicTerm{name, stems, score})
+				break
+			}
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i].name < terms[j].name })
+	return terms
+}
+
+// matchTopics sums the scores of the topics found in an item's title or
+// description and names them.
+func matchTopics(terms []topicTerm, it *FeedItem) (sum float64, names []string) {
+	title, desc := analyze(it.Title), analyze(stripHTML(it.Description))
+	for _, t := range terms {
+		if containsSeq(title, t.stems) || containsSeq(desc, t.stems) {
+			sum += t.score
+			names = append(names, t.name)
+		}
+	}
+	return sum, names
+}
+
+// topicAffinity maps a summed topic score onto 0–1.
+func topicAffinity(sum float64) float64 {
+	return 1 - math.Exp(-sum/personalizeSaturation)
+}
+
+/* ── user topics ── */
+
+type topicCacheEntry struct {
+	topics map[string]float64
+	at     time.Time
+}
+
+var (
+	topicCache   = make(map[int]topicCacheEntry)
+	topicCacheMu sync.Mutex
+)
+
+// userTopics returns the caller's positively scored topics, decayed to
+// now and cached for topicCacheTTL so typing and paging don't hit the DB on
+// every request.
+func userTopics(ctx context.Context, userID int) map[string]float64 {
+	topicCacheMu.Lock()
+	e, ok := topicCache[userID]
+	topicCacheMu.Unlock()
+	if ok && time.Since(e.at) < topicCacheTTL {
+		return e.topics
+	}
+
+	topics := make(map[string]float64)
+	scores, err := auth.TopicScores(ctx, userID, 200)
+	if err != nil {
+		log.Printf("⚠️ Could not load topics for user %d: %v", userID, err)
+		return topics
+	}
+	for _, t := range scores {
+		if t.Score > 0 {
+			topics[strings.ToLower(t.Topic)] = t.Score
+		}
+	}
+
+	topicCacheMu.Lock()
+	topicCache[userID] = topicCacheEntry{topics, time.Now()}
+	topicCacheMu.Unlock()
+	return topics
+}
@@ -0,0 +1,622 @@
+package feeds
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/* ───────────────── QUERY LANGUAGE ────────────────────────── */
+
+// Grammar (keywords are upper-case, like most search engines):
+//
+//	query   := or
+//	or      := and { ("OR" | ",") and }
+//	and     := unary { ["AND"] unary }           juxtaposition means AND
+//	unary   := ("NOT" | "-") unary | primary
+//	primary := "(" or ")" | field ":" value | "\"phrase\"" | word
+//	field   := title | source | category | after | before
+//
+// after:/before: take YYYY-MM-DD; after is inclusive, before exclusive.
+// A bare comma is an OR so the old "a, b, c" queries keep working.
+
+// QueryNode is one node of a parsed query.
+type QueryNode struct {
+	Op       string       `json:"op"`              // and | or | not | term | phrase
+	Field    string       `json:"field,omitempty"` // "" = any text field
+	Value    string       `json:"value,omitempty"`
+	Children []*QueryNode `json:"children,omitempty"`
+
+	stems []string    // term / phrase, analyzed like the index
+	alts  []expansion // equivalents from the expansion file
+	date  time.Time   // after / before
+}
+
+// QueryError is a malformed query; /feed turns it into a 400.
+type QueryError struct {
+	Pos int    `json:"pos"`
+	Msg string `json:"error"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+var queryFields = map[string]bool{
+	"title": true, "source": true, "category": true, "after": true, "before": true,
+}
+
+/* ── lexer ── */
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokField // value holds the field name; the next token is its value
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+func lexQuery(q string) ([]token, error) {
+	var toks []token
+	rs := []rune(q)
+	i := 0
+	for i < len(rs) {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case r == ',':
+			toks = append(toks, token{tokOr, ",", i})
+			i++
+		case r == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]):
+			// only reached at the start of a token; "f-35" is lexed as one word
+			toks = append(toks, token{tokNot, "-", i})
+			i++
+		case r == '"':
+			start := i
+			i++
+			j := i
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, &QueryError{Pos: start, Msg: "unterminated quote"}
+			}
+			toks = append(toks, token{tokPhrase, string(rs[i:j]), start})
+			i = j + 1
+		default:
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) && !strings.ContainsRune(`()",`, rs[i]) {
+				i++
+			}
+			word := string(rs[start:i])
+
+			if k := strings.IndexRune(word, ':'); k > 0 && queryFields[strings.ToLower(word[:k])] {
+				toks = append(toks, token{tokField, strings.ToLower(word[:k]), start})
+				if rest := word[k+1:]; rest != "" {
+					toks = append(toks, token{tokWord, rest, start + k + 1})
+				}
+				continue
+			}
+
+			switch word {
+			case "AND":
+				toks = append(toks, token{tokAnd, word, start})
+			case "OR":
+				toks = append(toks, token{tokOr, word, start})
+			case "NOT":
+				toks = append(toks, token{tokNot, word, start})
+			default:
+				toks = append(toks, token{tokWord, word, start})
+			}
+		}
+	}
+	return append(toks, token{tokEOF, "", len(rs)}), nil
+}
+
+/* ── parser ── */
+
+type queryParser struct {
+	toks []token
+	pos  int
+}
+
+// ParseQuery turns a search string into an AST.
+func ParseQuery(q string) (*QueryNode, error) {
+	toks, err := lexQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, &QueryError{Pos: 0, Msg: "empty query"}
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		if t.kind == tokRParen {
+			return nil, &QueryError{Pos: t.pos, Msg: "unbalanced ')'"}
+		}
+		return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.val)}
+	}
+	return n, nil
+}
+
+func (p *queryParser) peek() token { return p.toks[p.pos] }
+func (p *queryParser) next() token { t := p.toks[p.pos]; p.pos++; return t }
+
+func (p *queryParser) parseOr() (*QueryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []*QueryNode{left}
+	for p.peek().kind == tokOr {
+		op := p.next()
+		if k := p.peek().kind; k == tokEOF || k == tokRParen || k == tokOr {
+			return nil, &QueryError{Pos: op.pos, Msg: "OR needs a term on both sides"}
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &QueryNode{Op: "or", Children: children}, nil
+}
+
+func (p *queryParser) parseAnd() (*QueryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []*QueryNode{left}
+	for {
+		t := p.peek()
+		if t.kind == tokAnd {
+			p.next()
+			if k := p.peek().kind; k == tokEOF || k == tokRParen || k == tokOr || k == tokAnd {
+				return nil, &QueryError{Pos: t.pos, Msg: "AND needs a term on both sides"}
+			}
+		} else if t.kind == tokEOF || t.kind == tokRParen || t.kind == tokOr {
+			break
+		} else if p.noiseBetweenTerms() {
+			p.next() // "research & development", "a - b": just the two words
+			continue
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &QueryNode{Op: "and", Children: children}, nil
+}
+
+// noiseBetweenTerms reports whether the next token is a word with nothing
+// to search for ("&", "/", a spaced "-") followed by another term. Called
+// after a term, so the word sits between two; anywhere else it is an
+// error.
+func (p *queryParser) noiseBetweenTerms() bool {
+	t := p.peek()
+	if t.kind != tokWord {
+		return false
+	}
+	if _, err := newTextNode("term", "", t.val, t.pos); err == nil {
+		return false
+	}
+	switch p.toks[p.pos+1].kind {
+	case tokEOF, tokRParen, tokOr, tokAnd:
+		return false
+	}
+	return true
+}
+
+func (p *queryParser) parseUnary() (*QueryNode, error) {
+	if t := p.peek(); t.kind == tokNot {
+		p.next()
+		if k := p.peek().kind; k == tokEOF || k == tokRParen || k == tokOr || k == tokAnd {
+			return nil, &QueryError{Pos: t.pos, Msg: "NOT needs a term after it"}
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &QueryNode{Op: "not", Children: []*QueryNode{child}}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (*QueryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		if p.peek().kind == tokRParen {
+			return nil, &QueryError{Pos: t.pos, Msg: "empty parentheses"}
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, &QueryError{Pos: t.pos, Msg: "unbalanced '('"}
+		}
+		p.next()
+		return n, nil
+
+	case tokField:
+		v := p.next()
+		if v.kind != tokWord && v.kind != tokPhrase {
+			return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("%s: needs a value", t.val)}
+		}
+		return newFieldNode(t, v)
+
+	case tokPhrase:
+		return newTextNode("phrase", "", t.val, t.pos)
+
+	case tokWord:
+		return newTextNode("term", "", t.val, t.pos)
+
+	case tokRParen:
+		return nil, &QueryError{Pos: t.pos, Msg: "unbalanced ')'"}
+	case tokEOF:
+		return nil, &QueryError{Pos: t.pos, Msg: "unexpected end of query"}
+	}
+	return nil, &QueryError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.val)}
+}
+
+func newFieldNode(field, v token) (*QueryNode, error) {
+	switch field.val {
+	case "title":
+		op := "term"
+		if v.kind == tokPhrase {
+			op = "phrase"
+		}
+		return newTextNode(op, "title", v.val, v.pos)
+
+	case "after", "before":
+		d, err := time.Parse("2006-01-02", v.val)
+		if err != nil {
+			return nil, &QueryError{Pos: v.pos, Msg: fmt.Sprintf("%s: wants a YYYY-MM-DD date", field.val)}
+		}
+		return &QueryNode{Op: "term", Field: field.val, Value: v.val, date: d}, nil
+
+	default: // source, category
+		val := strings.ToLower(strings.TrimSpace(v.val))
+		if val == "" {
+			return nil, &QueryError{Pos: v.pos, Msg: fmt.Sprintf("%s: needs a value", field.val)}
+		}
+		return &QueryNode{Op: "term", Field: field.val, Value: val}, nil
+	}
+}
+
+// newTextNode builds a term or phrase matcher. Stray punctuation at the
+// edges ("ukraine:" or "drones.") is dropped, and the words are analyzed the
+// same way as indexed text so "contracts" finds "contracting".
+func newTextNode(op, field, raw string, pos int) (*QueryNode, error) {
+	words := strings.Fields(strings.ToLower(raw))
+	for i, w := range words {
+		words[i] = strings.TrimFunc(w, func(r rune) bool {
+			return unicode.IsPunct(r) && r != '+' && r != '#'
+		})
+	}
+	var kept []string
+	for _, w := range words {
+		if w != "" {
+			kept = append(kept, w)
+		}
+	}
+	value := strings.Join(kept, " ")
+	stems := analyze(value)
+	if len(stems) == 0 {
+		return nil, &QueryError{Pos: pos, Msg: fmt.Sprintf("%q has nothing to search for", raw)}
+	}
+	return &QueryNode{Op: op, Field: field, Value: value, stems: stems, alts: expansionsFor(stems)}, nil
+}
+
+/* ── matcher ── */
+
+// matchDoc is a FeedItem prepared for matching: its text fields as the
+// index's analyzed token streams. desc and content are only consulted in deep mode; quick
+// mode looks at titles alone.
+type matchDoc struct {
+	item    *FeedItem
+	title   []string
+	desc    []string
+	content []string
+	host    string
+	deep    bool
+	noFuzzy bool // match as typed, ignoring fuzzy corrections
+}
+
+// newMatchDoc analyzes an item outside the index, the same way add does.
+func newMatchDoc(it *FeedItem, deep bool) *matchDoc {
+	d := &matchDoc{
+		item:    it,
+		title:   analyze(it.Title),
+		desc:    analyze(stripHTML(it.Description)),
+		content: analyze(it.Content),
+		deep:    deep,
+	}
+	if u, err := url.Parse(it.Link); err == nil {
+		d.host = strings.ToLower(u.Host)
+	}
+	return d
+}
+
+// containsSeq reports whether seq occurs as consecutive tokens in toks.
+func containsSeq(toks, seq []string) bool {
+	for i := 0; i+len(seq) <= len(toks); i++ {
+		match := true
+		for j, s := range seq {
+			if toks[i+j] != s {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// in reports whether the term, or one of its expansions, occurs in toks.
+// Fuzzy expansions only count when withFuzzy is set.
+func (n *QueryNode) in(toks []string, withFuzzy bool) bool {
+	if containsSeq(toks, n.stems) {
+		return true
+	}
+	for _, a := range n.alts {
+		if (withFuzzy || !a.fuzzy) && containsSeq(toks, a.stems) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchedFields lists which text fields any of terms hit.
+func (d *matchDoc) matchedFields(terms []*QueryNode) []string {
+	var inTitle, inDesc, inContent bool
+	for _, t := range terms {
+		inTitle = inTitle || t.in(d.title, true)
+		if d.deep && t.Field == "" {
+			inDesc = inDesc || t.in(d.desc, true)
+			inContent = inContent || t.in(d.content, true)
+		}
+	}
+	var out []string
+	if inTitle {
+		out = append(out, "title")
+	}
+	if inDesc {
+		out = append(out, "description")
+	}
+	if inContent {
+		out = append(out, "content")
+	}
+	return out
+}
+
+// Match evaluates the query against one item.
+func (n *QueryNode) Match(d *matchDoc) bool {
+	switch n.Op {
+	case "and":
+		for _, c := range n.Children {
+			if !c.Match(d) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, c := range n.Children {
+			if c.Match(d) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !n.Children[0].Match(d)
+	}
+
+	switch n.Field {
+	case "":
+		fz := !d.noFuzzy
+		return n.in(d.title, fz) || (d.deep && (n.in(d.desc, fz) || n.in(d.content, fz)))
+	case "title":
+		return n.in(d.title, !d.noFuzzy)
+	case "source":
+		return strings.Contains(strings.ToLower(d.item.Source), n.Value) || strings.Contains(d.host, n.Value)
+	case "category":
+		return strings.EqualFold(d.item.Category, n.Value)
+	case "after":
+		return !d.item.Published.IsZero() && !d.item.Published.Before(n.date)
+	case "before":
+		return !d.item.Published.IsZero() && d.item.Published.Before(n.date)
+	}
+	return false
+}
+
+// positiveTextNodes lists the term/phrase nodes that aren't negated; these
+// are the ones that drive scoring and "matched fields".
+func (n *QueryNode) positiveTextNodes() []*QueryNode {
+	var out []*QueryNode
+	var walk func(*QueryNode)
+	walk = func(n *QueryNode) {
+		switch n.Op {
+		case "not":
+			return
+		case "and", "or":
+			for _, c := range n.Children {
+				walk(c)
+			}
+		default:
+			if n.stems != nil {
+				out = append(out, n)
+			}
+		}
+	}
+	walk(n)
+	return out
+}
+
+// needsText reports whether every match must contain at least one
+// positive term or phrase, i.e. whether the index postings of those terms
+// are a complete candidate set.
+func (n *QueryNode) needsText() bool {
+	switch n.Op {
+	case "and":
+		for _, c := range n.Children {
+			if c.needsText() {
+				return true
+			}
+		}
+		return false
+	case "or":
+		for _, c := range n.Children {
+			if !c.needsText() {
+				return false
+			}
+		}
+		return len(n.Children) > 0
+	case "not":
+		return false
+	}
+	for _, s := range n.stems {
+		if !stopwords[s] {
+			return true
+		}
+	}
+	return false
+}
+
+/* ── expansion ── */
+
+// expand=true ORs related keywords into the query's plain text only: the
+// top-level clauses with no negation or field in them. Everything else
+// (-terms, NOT, title:, source:, after: …) stays ANDed with the result, so
+// it keeps applying to every alternative:
+//
+//	drone -china after:2026-01-01  +  [uav]
+//	→ (drone OR "uav") AND NOT china AND after:2026-01-01
+
+// ExpansionText is the part of query related keywords should be found for:
+// the words of its plain-text clauses. It is "" when there are none, or
+// when the query doesn't parse.
+func ExpansionText(query string) string {
+	ast, err := ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	text, _ := splitExpandable(ast)
+	var words []string
+	for _, n := range text {
+		for _, t := range n.positiveTextNodes() {
+			words = append(words, t.Value)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// expandWith ORs keywords, each as a phrase, into the plain-text clauses of
+// ast. Keywords with nothing to search for, or that repeat a term already
+// there, are skipped.
+func expandWith(ast *QueryNode, keywords []string) *QueryNode {
+	text, rest := splitExpandable(ast)
+	if len(text) == 0 {
+		return ast
+	}
+	seen := make(map[string]bool)
+	for _, n := range text {
+		for _, t := range n.positiveTextNodes() {
+			seen[strings.Join(t.stems, " ")] = true
+		}
+	}
+
+	alts := []*QueryNode{andOf(text)}
+	for _, kw := range keywords {
+		n, err := newTextNode("phrase", "", kw, 0)
+		if err != nil || seen[strings.Join(n.stems, " ")] {
+			continue
+		}
+		seen[strings.Join(n.stems, " ")] = true
+		alts = append(alts, n)
+	}
+	if len(alts) == 1 {
+		return ast
+	}
+	return andOf(append([]*QueryNode{{Op: "or", Children: alts}}, rest...))
+}
+
+// splitExpandable flattens ast's top-level ANDs and sorts the clauses into
+// plain text and everything else.
+func splitExpandable(ast *QueryNode) (text, rest []*QueryNode) {
+	var walk func(*QueryNode)
+	walk = func(n *QueryNode) {
+		switch {
+		case n.Op == "and" && len(n.Children) > 0:
+			for _, c := range n.Children {
+				walk(c)
+			}
+		case n.plainText():
+			text = append(text, n)
+		default:
+			rest = append(rest, n)
+		}
+	}
+	walk(ast)
+	return text, rest
+}
+
+// plainText reports whether n is only unqualified terms and phrases joined
+// by AND/OR.
+func (n *QueryNode) plainText() bool {
+	switch n.Op {
+	case "and", "or":
+		for _, c := range n.Children {
+			if !c.plainText() {
+				return false
+			}
+		}
+		return len(n.Children) > 0
+	case "not":
+		return false
+	}
+	return n.Field == "" && n.stems != nil
+}
+
+func andOf(nodes []*QueryNode) *QueryNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return &QueryNode{Op: "and", Children: nodes}
+}
@@ -0,0 +1,217 @@
+package feeds
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLexQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"hypersonic missile", "word(hypersonic)@0 word(missile)@11"},
+		{"f-35 -china", "word(f-35)@0 not(-)@5 word(china)@6"},
+		{"a - b", "word(a)@0 word(-)@2 word(b)@4"},
+		{`title:"b-21 raider"`, "field(title)@0 phrase(b-21 raider)@6"},
+		{"source:nsf.gov", "field(source)@0 word(nsf.gov)@7"},
+		{"ukraine: aid", "word(ukraine:)@0 word(aid)@9"},
+		{"(a OR b), c", "lparen(()@0 word(a)@1 or(OR)@3 word(b)@6 rparen())@7 or(,)@8 word(c)@10"},
+		{"NOT a AND b", "not(NOT)@0 word(a)@4 and(AND)@6 word(b)@10"},
+		{"a or b", "word(a)@0 word(or)@2 word(b)@5"},
+		{"Ωmega β", "word(Ωmega)@0 word(β)@6"},
+	}
+	kinds := map[tokenKind]string{
+		tokWord: "word", tokPhrase: "phrase", tokField: "field", tokLParen: "lparen",
+		tokRParen: "rparen", tokAnd: "and", tokOr: "or", tokNot: "not",
+	}
+	for _, tt := range tests {
+		toks, err := lexQuery(tt.query)
+		if err != nil {
+			t.Errorf("lexQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if last := toks[len(toks)-1]; last.kind != tokEOF || last.pos != len([]rune(tt.query)) {
+			t.Errorf("lexQuery(%q) ends with %+v, want EOF at %d", tt.query, last, len([]rune(tt.query)))
+		}
+		var got []string
+		for _, tok := range toks[:len(toks)-1] {
+			got = append(got, fmt.Sprintf("%s(%s)@%d", kinds[tok.kind], tok.val, tok.pos))
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("lexQuery(%q) = %s, want %s", tt.query, strings.Join(got, " "), tt.want)
+		}
+	}
+}
+
+// show prints an AST compactly: terms bare, phrases quoted, fields as
+// field:value, groups in parentheses.
+func show(n *QueryNode) string {
+	switch n.Op {
+	case "and", "or":
+		parts := make([]string, len(n.Children))
+		for i, c := range n.Children {
+			parts[i] = show(c)
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(n.Op)+" ") + ")"
+	case "not":
+		return "-" + show(n.Children[0])
+	}
+	v := n.Value
+	if n.Op == "phrase" {
+		v = `"` + v + `"`
+	}
+	if n.Field != "" {
+		return n.Field + ":" + v
+	}
+	return v
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"hypersonic missile", "(hypersonic AND missile)"},
+		{"hypersonic AND missile", "(hypersonic AND missile)"},
+		{"a OR b c", "(a OR (b AND c))"},
+		{"a, b, c", "(a OR b OR c)"},
+		{"(drone OR uav) swarm", "((drone OR uav) AND swarm)"},
+		{"-russia NOT china", "(-russia AND -china)"},
+		{"NOT NOT a", "--a"},
+		{`"glide vehicle" title:f-35`, `("glide vehicle" AND title:f-35)`},
+		{`title:"b-21 raider"`, `title:"b-21 raider"`},
+		{"source:NSF.gov category:Grant", "(source:nsf.gov AND category:grant)"},
+		{"after:2026-01-01 before:2026-02-01", "(after:2026-01-01 AND before:2026-02-01)"},
+		{"ukraine: drones.", "(ukraine AND drones)"},
+		{"research & development", "(research AND development)"},
+		{"a - b", "(a AND b)"},
+		{"a & / b", "(a AND b)"},
+		{"a & -b", "(a AND -b)"},
+	}
+	for _, tt := range tests {
+		n, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if got := show(n); got != tt.want {
+			t.Errorf("ParseQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{"", 0, "empty query"},
+		{"   ", 0, "empty query"},
+		{`"open`, 0, "unterminated quote"},
+		{"drone \"open", 6, "unterminated quote"},
+		{"&", 0, "has nothing to search for"},
+		{"-&", 1, "has nothing to search for"},
+		{"drone &", 6, "has nothing to search for"},
+		{"& drone", 0, "has nothing to search for"},
+		{"a & OR b", 2, "has nothing to search for"},
+		{"(&) a", 1, "has nothing to search for"},
+		{`""`, 0, "has nothing to search for"},
+		{"a OR", 2, "OR needs a term on both sides"},
+		{", a", 0, "unexpected \",\""},
+		{"a AND", 2, "AND needs a term on both sides"},
+		{"a NOT", 2, "NOT needs a term after it"},
+		{"(a OR b", 0, "unbalanced '('"},
+		{"a)", 1, "unbalanced ')'"},
+		{"()", 0, "empty parentheses"},
+		{"title:", 0, "title: needs a value"},
+		{"source:()", 0, "source: needs a value"},
+		{"after:2026-13-01", 6, "after: wants a YYYY-MM-DD date"},
+		{"drone before:soon", 13, "before: wants a YYYY-MM-DD date"},
+	}
+	for _, tt := range tests {
+		n, err := ParseQuery(tt.query)
+		var qerr *QueryError
+		if !errors.As(err, &qerr) {
+			t.Errorf("ParseQuery(%q) = %v, %v; want a QueryError", tt.query, n, err)
+			continue
+		}
+		if qerr.Pos != tt.pos || !strings.Contains(qerr.Msg, tt.msg) {
+			t.Errorf("ParseQuery(%q) error = %d %q, want %d %q", tt.query, qerr.Pos, qerr.Msg, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestExpansionText(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"hypersonic missile", "hypersonic missile"},
+		{"drone -china after:2026-01-01", "drone"},
+		{"drone NOT (china OR russia) source:army", "drone"},
+		{`"space force" budget category:news`, "space force budget"},
+		{"(drone OR uav) title:swarm", "drone uav"},
+		{"-china", ""},
+		{"after:2026-01-01", ""},
+		{`"unterminated`, ""},
+	}
+	for _, tt := range tests {
+		if got := ExpansionText(tt.query); got != tt.want {
+			t.Errorf("ExpansionText(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExpandWithKeepsFilters(t *testing.T) {
+	ast, err := ParseQuery("drone -china after:2026-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// what a local fallback on the whole query used to produce
+	ast = expandWith(ast, []string{"drone", "china", "after2026", "unmanned aircraft"})
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		name string
+		item FeedItem
+		want bool
+	}{
+		{"original term", FeedItem{Title: "Army buys drone swarm", Published: day("2026-03-01")}, true},
+		{"expansion", FeedItem{Title: "New unmanned aircraft tested", Published: day("2026-03-01")}, true},
+		{"excluded term", FeedItem{Title: "China drone exports", Published: day("2026-03-01")}, false},
+		{"expansion plus excluded term", FeedItem{Title: "China unmanned aircraft", Published: day("2026-03-01")}, false},
+		{"before the date filter", FeedItem{Title: "China drone exports", Published: day("2020-06-01")}, false},
+		{"expansion before the date filter", FeedItem{Title: "Unmanned aircraft rules", Published: day("2020-06-01")}, false},
+		{"unrelated", FeedItem{Title: "Navy shipbuilding", Published: day("2026-03-01")}, false},
+	}
+	for _, tt := range tests {
+		if got := ast.Match(newMatchDoc(&tt.item, false)); got != tt.want {
+			t.Errorf("%s: Match(%q) = %v, want %v", tt.name, tt.item.Title, got, tt.want)
+		}
+	}
+}
+
+func TestExpandWithSkipsDuplicates(t *testing.T) {
+	ast, err := ParseQuery("drones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := expandWith(ast, []string{"drone", `""`, "&"}); got != ast {
+		t.Errorf("expandWith added only repeats and empties, got %+v", got)
+	}
+	got := expandWith(ast, []string{"uav", "drone"})
+	if got.Op != "or" || len(got.Children) != 2 {
+		t.Fatalf("expandWith = %+v, want drones OR \"uav\"", got)
+	}
+	if c := got.Children[1]; c.Op != "phrase" || c.Value != "uav" {
+		t.Errorf("expansion node = %+v, want phrase uav", c)
+	}
+}
@@ -0,0 +1,269 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── QUERY LOG ─────────────────────────────── */
+
+// Every /feed search is logged to `query_log` so admins can see what people
+// look for and what comes back empty. Queries are grouped by their
+// normalized terms: the analyzed, de-duplicated, sorted search words, so
+// "Hypersonic Missiles" and "missile hypersonic" count as one query.
+// result_count is the literal matches: a misspelling only fuzzy matching
+// rescued still counts as zero results. Searches that failed (a malformed
+// query, a DB error) are logged with their error and kept out of the
+// zero-result counts.
+
+const (
+	queryLogTimeout     = 5 * time.Second
+	defaultReportWindow = "7d"
+	defaultReportLimit  = 20
+	maxReportLimit      = 200
+)
+
+// QueryLogEntry is one logged search.
+type QueryLogEntry struct {
+	UserID       *int
+	Query        string
+	Results      int
+	Latency      time.Duration
+	DeepFallback bool
+	Expanded     bool
+	Error        string // set when the search failed
+}
+
+// InitQueryLog creates the `query_log` table.
+func InitQueryLog() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS query_log (
+			id            BIGSERIAL PRIMARY KEY,
+			user_id       INTEGER,
+			query         TEXT NOT NULL,
+			normalized    TEXT NOT NULL,
+			terms         TEXT[] NOT NULL DEFAULT '{}',
+			result_count  INTEGER NOT NULL,
+			latency_ms    INTEGER NOT NULL,
+			deep_fallback BOOLEAN NOT NULL DEFAULT FALSE,
+			expanded      BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`ALTER TABLE query_log ADD COLUMN IF NOT EXISTS failed BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE query_log ADD COLUMN IF NOT EXISTS error  TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS query_log_created_at_idx ON query_log (created_at)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := auth.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate query log: %w", err)
+		}
+	}
+	return nil
+}
+
+// LogQuery records e in the background; a failed write is only logged, the
+// search it describes has already been answered.
+func LogQuery(e QueryLogEntry) {
+	terms := queryTerms(e.Query)
+	normalized := strings.Join(terms, " ")
+	if normalized == "" {
+		normalized = strings.Join(strings.Fields(strings.ToLower(e.Query)), " ")
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), queryLogTimeout)
+		defer cancel()
+		_, err := auth.DB.ExecContext(ctx, `
+			INSERT INTO query_log (user_id, query, normalized, terms, result_count, latency_ms,
+			                       deep_fallback, expanded, failed, error)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, e.UserID, e.Query, normalized, pq.Array(terms), e.Results, e.Latency.Milliseconds(),
+			e.DeepFallback, e.Expanded, e.Error != "", e.Error)
+		if err != nil {
+			log.Printf("⚠️ Could not log query [%s]: %v", e.Query, err)
+		}
+	}()
+}
+
+// queryTerms lists the searched-for words and phrases of query, analyzed
+// and sorted. Negated terms and field filters don't count.
+func queryTerms(query string) []string {
+	ast, err := ParseQuery(query)
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range ast.positiveTextNodes() {
+		term := strings.Join(t.stems, " ")
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+/* ── admin report ── */
+
+// QueryStat is one normalized query in the report.
+type QueryStat struct {
+	Query      string    `json:"query"` // most recent spelling
+	Terms      string    `json:"terms"` // normalized form it was grouped by
+	Count      int       `json:"count"` // searches in the window
+	Users      int       `json:"users"` // distinct logged-in users
+	AvgResults float64   `json:"avg_results"`
+	LastSeen   time.Time `json:"last_seen"`
+	Error      string    `json:"error,omitempty"` // latest error, for failures
+}
+
+// SlowQuery is one of the slowest individual searches.
+type SlowQuery struct {
+	Query        string    `json:"query"`
+	LatencyMs    int       `json:"latency_ms"`
+	Results      int       `json:"result_count"`
+	DeepFallback bool      `json:"deep_fallback"`
+	At           time.Time `json:"at"`
+}
+
+// SearchReport summarizes the query log over a window.
+type SearchReport struct {
+	Since        time.Time   `json:"since"`
+	Total        int         `json:"total"`
+	ZeroResult   int         `json:"zero_result"`
+	Failed       int         `json:"failed"`
+	DeepFallback int         `json:"deep_fallback"`
+	P50Ms        float64     `json:"p50_ms"`
+	P95Ms        float64     `json:"p95_ms"`
+	Top          []QueryStat `json:"top"`
+	ZeroResults  []QueryStat `json:"zero_results"`
+	Failures     []QueryStat `json:"failures"`
+	Slowest      []SlowQuery `json:"slowest"`
+}
+
+// SearchReportHandler serves GET /admin/search-report?window=7d&limit=20.
+// window takes an age (7d, 12h) or a YYYY-MM-DD start date.
+func SearchReportHandler(c *gin.Context) {
+	since, err := parseFilterTime(c.DefaultQuery("window", defaultReportWindow), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window: " + err.Error()})
+		return
+	}
+	limit := defaultReportLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(n, maxReportLimit)
+	}
+
+	rep, err := searchReport(c.Request.Context(), since, limit)
+	if err != nil {
+		log.Printf("❌ Search report failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
+func searchReport(ctx context.Context, since time.Time, limit int) (*SearchReport, error) {
+	rep := &SearchReport{Since: since}
+
+	var p50, p95 sql.NullFloat64
+	err := auth.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE result_count = 0 AND NOT failed),
+		       COUNT(*) FILTER (WHERE failed),
+		       COUNT(*) FILTER (WHERE deep_fallback),
+		       percentile_cont(0.5)  WITHIN GROUP (ORDER BY latency_ms),
+		       percentile_cont(0.95) WITHIN GROUP (ORDER BY latency_ms)
+		FROM query_log
+		WHERE created_at >= $1
+	`, since).Scan(&rep.Total, &rep.ZeroResult, &rep.Failed, &rep.DeepFallback, &p50, &p95)
+	if err != nil {
+		return nil, err
+	}
+	rep.P50Ms, rep.P95Ms = p50.Float64, p95.Float64
+
+	if rep.Top, err = queryStats(ctx, since, limit, statsAll); err != nil {
+		return nil, err
+	}
+	if rep.ZeroResults, err = queryStats(ctx, since, limit, statsZeroResults); err != nil {
+		return nil, err
+	}
+	if rep.Failures, err = queryStats(ctx, since, limit, statsFailed); err != nil {
+		return nil, err
+	}
+
+	rows, err := auth.DB.QueryContext(ctx, `
+		SELECT query, latency_ms, result_count, deep_fallback, created_at
+		FROM query_log
+		WHERE created_at >= $1
+		ORDER BY latency_ms DESC, created_at DESC
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rep.Slowest = []SlowQuery{}
+	for rows.Next() {
+		var s SlowQuery
+		if err := rows.Scan(&s.Query, &s.LatencyMs, &s.Results, &s.DeepFallback, &s.At); err != nil {
+			return nil, err
+		}
+		rep.Slowest = append(rep.Slowest, s)
+	}
+	return rep, rows.Err()
+}
+
+// Which searches queryStats counts.
+const (
+	statsAll         = `TRUE`
+	statsZeroResults = `result_count = 0 AND NOT failed`
+	statsFailed      = `failed`
+)
+
+// queryStats groups the window's searches that meet which (one of the
+// stats* conditions) by normalized terms, most frequent first.
+func queryStats(ctx context.Context, since time.Time, limit int, which string) ([]QueryStat, error) {
+	rows, err := auth.DB.QueryContext(ctx, `
+		SELECT (array_agg(query ORDER BY created_at DESC))[1], normalized,
+		       COUNT(*), COUNT(DISTINCT user_id), AVG(result_count)::float8, MAX(created_at),
+		       (array_agg(error ORDER BY created_at DESC))[1]
+		FROM query_log
+		WHERE created_at >= $1 AND normalized <> '' AND `+which+`
+		GROUP BY normalized
+		ORDER BY COUNT(*) DESC, MAX(created_at) DESC
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []QueryStat{}
+	for rows.Next() {
+		var s QueryStat
+		if err := rows.Scan(&s.Query, &s.Terms, &s.Count, &s.Users, &s.AvgResults, &s.LastSeen, &s.Error); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,205 @@
+package feeds
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── RELATED ARTICLES ──────────────────────── */
+
+// "More like this": the article's keywords (the same extractor that feeds
+// user topics) pull candidates from the index postings, and candidates are
+// ranked by TF-IDF cosine similarity over title and description, title
+// words weighing titleBoost. A near-identical article from the same source
+// is the same story re-posted, not related coverage, so it is dropped.
+
+const (
+	defaultRelated   = 5
+	maxRelated       = 20
+	relatedSeeds     = 12   // rarest keywords used to gather candidates
+	relatedMinCosine = 0.05 // below this, overlap is a stray common word
+	sameStoryCosine  = 0.8
+)
+
+// RelatedArticle is a stored article and how similar it is to the one
+// asked about (cosine, 0–1).
+type RelatedArticle struct {
+	FeedItem
+	Similarity float64 `json:"similarity"`
+}
+
+// termVector is the unit-length TF-IDF vector of a title and description.
+// Callers hold idx.mu.
+func (idx *invertedIndex) termVector(title, desc []string) map[string]float64 {
+	v := make(map[string]float64)
+	for _, t := range title {
+		if !stopwords[t] {
+			v[t] += titleBoost
+		}
+	}
+	for _, t := range desc {
+		if !stopwords[t] {
+			v[t]++
+		}
+	}
+
+	var norm float64
+	for t, tf := range v {
+		v[t] = tf * idx.idf(t)
+		norm += v[t] * v[t]
+	}
+	norm = math.Sqrt(norm)
+	for t := range v {
+		if norm > 0 {
+			v[t] /= norm
+		}
+	}
+	return v
+}
+
+func (idx *invertedIndex) idf(stem string) float64 {
+	df := max(len(idx.Title[stem]), len(idx.Body[stem]), 1)
+	return math.Log(1 + float64(idx.Live)/float64(df))
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot float64
+	for t, w := range a {
+		dot += w * b[t]
+	}
+	return dot
+}
+
+// related returns up to limit articles similar to target, which came from
+// source sourceID.
+func (idx *invertedIndex) related(target FeedItem, sourceID int, sourceIDs map[int]bool, limit int) []RelatedArticle {
+	desc := stripHTML(target.Description)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	tvec := idx.termVector(analyze(target.Title), analyze(desc))
+
+	/* candidates: docs sharing one of the rarest keywords */
+	var seeds []string
+	seen := make(map[string]bool)
+	for _, kw := range auth.ExtractKeywords(target.Title + " " + desc) {
+		for _, s := range analyze(kw) {
+			if !seen[s] && !stopwords[s] {
+				seen[s] = true
+				seeds = append(seeds, s)
+			}
+		}
+	}
+	sort.Slice(seeds, func(i, j int) bool {
+		if a, b := idx.idf(seeds[i]), idx.idf(seeds[j]); a != b {
+			return a > b
+		}
+		return seeds[i] < seeds[j]
+	})
+	if len(seeds) > relatedSeeds {
+		seeds = seeds[:relatedSeeds]
+	}
+
+	candidates := make(map[int]bool)
+	for _, s := range seeds {
+		for id := range idx.Title[s] {
+			candidates[id] = true
+		}
+		for id := range idx.Body[s] {
+			candidates[id] = true
+		}
+	}
+
+	type scored struct {
+		doc *indexDoc
+		vec map[string]float64
+		sim float64
+	}
+	var hits []scored
+	for id := range candidates {
+		doc := idx.Docs[id]
+		if doc.Deleted || !sourceIDs[doc.SourceID] || doc.Item.Link == target.Link {
+			continue
+		}
+		vec := idx.termVector(doc.Title, doc.Desc)
+		sim := cosine(tvec, vec)
+		if sim < relatedMinCosine || (doc.SourceID == sourceID && sim >= sameStoryCosine) {
+			continue
+		}
+		hits = append(hits, scored{doc, vec, sim})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].sim != hits[j].sim {
+			return hits[i].sim > hits[j].sim
+		}
+		if !hits[i].doc.Item.Published.Equal(hits[j].doc.Item.Published) {
+			return hits[i].doc.Item.Published.After(hits[j].doc.Item.Published)
+		}
+		return hits[i].doc.Item.Link < hits[j].doc.Item.Link
+	})
+
+	/* one copy per story per source among the results too */
+	out := []RelatedArticle{}
+	var picked []scored
+next:
+	for _, h := range hits {
+		if len(out) == limit {
+			break
+		}
+		for _, p := range picked {
+			if p.doc.SourceID == h.doc.SourceID && cosine(p.vec, h.vec) >= sameStoryCosine {
+				continue next
+			}
+		}
+		picked = append(picked, h)
+		out = append(out, RelatedArticle{FeedItem: h.doc.Item, Similarity: math.Round(h.sim*1000) / 1000})
+	}
+	return out
+}
+
+// RelatedHandler serves GET /related?link=&limit=.
+func RelatedHandler(c *gin.Context) {
+	link := c.Query("link")
+	if link == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "link is required"})
+		return
+	}
+	limit := defaultRelated
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(n, maxRelated)
+	}
+
+	var target FeedItem
+	var sourceID sql.NullInt64
+	err := auth.DB.QueryRow(`
+		SELECT link, title, description, source, source_id
+		FROM articles WHERE link = $1
+	`, link).Scan(&target.Link, &target.Title, &target.Description, &target.Source, &sourceID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	} else if err != nil {
+		log.Printf("❌ Related lookup for %s failed: %v", link, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+
+	items := searchIndex.related(target, int(sourceID.Int64), enabledSourceIDs(), limit)
+	c.JSON(http.StatusOK, gin.H{"link": link, "items": items})
+}
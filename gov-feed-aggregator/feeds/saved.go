@@ -0,0 +1,491 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── SAVED SEARCHES ────────────────────────── */
+
+// A saved search is re-run by the scheduler against articles ingested
+// since its last run; hits land in saved_search_matches, and anything
+// matched after last_viewed_at counts as new.
+
+const (
+	savedSearchTick    = time.Minute
+	maxSavedSearches   = 50
+	savedSearchOverlap = 5 * time.Minute // re-scan window for late-committing ingests
+)
+
+// SavedSearch is one row of `saved_searches`, plus its new-match count.
+type SavedSearch struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Query        string    `json:"query"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	LastViewedAt time.Time `json:"last_viewed_at"`
+	NewCount     int       `json:"new_count"`
+}
+
+// InitSavedSearches creates the saved-search tables.
+func InitSavedSearches() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS saved_searches (
+			id             SERIAL PRIMARY KEY,
+			user_id        INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name           TEXT NOT NULL,
+			query          TEXT NOT NULL,
+			created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_run_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_viewed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS saved_searches_user_idx ON saved_searches (user_id)`,
+		`CREATE INDEX IF NOT EXISTS articles_ingested_at_idx ON articles (ingested_at)`,
+		`CREATE TABLE IF NOT EXISTS saved_search_matches (
+			search_id  INTEGER NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+			link       TEXT NOT NULL,
+			matched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (search_id, link)
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := auth.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate saved searches: %w", err)
+		}
+	}
+	return nil
+}
+
+/* ── scheduler ── */
+
+// StartSavedSearchScheduler re-runs saved searches every savedSearchTick
+// until ctx is cancelled. Run it in a goroutine.
+func StartSavedSearchScheduler(ctx context.Context) {
+	log.Printf("⏰ Saved-search scheduler started (tick %v)", savedSearchTick)
+
+	ticker := time.NewTicker(savedSearchTick)
+	defer ticker.Stop()
+
+	for {
+		if err := runSavedSearches(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("❌ Saved-search run failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("🛑 Saved-search scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type savedSearchRun struct {
+	id    int
+	ast   *QueryNode
+	since time.Time
+}
+
+// runSavedSearches matches every saved search against the articles
+// ingested since its last run (minus savedSearchOverlap) and records hits.
+func runSavedSearches(ctx context.Context) error {
+	var now time.Time
+	if err := auth.DB.QueryRowContext(ctx, `SELECT NOW()`).Scan(&now); err != nil {
+		return err
+	}
+
+	rows, err := auth.DB.QueryContext(ctx, `SELECT id, query, last_run_at FROM saved_searches`)
+	if err != nil {
+		return err
+	}
+	var runs []savedSearchRun
+	var ids []int64
+	oldest := now
+	for rows.Next() {
+		var r savedSearchRun
+		var query string
+		if err := rows.Scan(&r.id, &query, &r.since); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, int64(r.id))
+		if r.ast, err = ParseQuery(query); err != nil {
+			continue // validated on save; skip rather than fail the batch
+		}
+		r.since = r.since.Add(-savedSearchOverlap)
+		if r.since.Before(oldest) {
+			oldest = r.since
+		}
+		runs = append(runs, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return err
+	}
+
+	items, ingested, err := loadIngestedSince(ctx, oldest, enabledSources())
+	if err != nil {
+		return err
+	}
+
+	docs := make([]*matchDoc, len(items))
+	for i := range items {
+		docs[i] = newMatchDoc(&items[i], true)
+	}
+
+	matched := 0
+	for _, r := range runs {
+		var links []string
+		for i, d := range docs {
+			if ingested[i].After(r.since) && r.ast.Match(d) {
+				links = append(links, items[i].Link)
+			}
+		}
+		if len(links) == 0 {
+			continue
+		}
+		res, err := auth.DB.ExecContext(ctx, `
+			INSERT INTO saved_search_matches (search_id, link)
+			SELECT $1, unnest($2::text[])
+			ON CONFLICT (search_id, link) DO NOTHING
+		`, r.id, pq.Array(links))
+		if err != nil {
+			return fmt.Errorf("record matches for saved search %d: %w", r.id, err)
+		}
+		n, _ := res.RowsAffected()
+		matched += int(n)
+	}
+
+	if _, err := auth.DB.ExecContext(ctx, `UPDATE saved_searches SET last_run_at = $2 WHERE id = ANY($1)`,
+		pq.Array(ids), now); err != nil {
+		return err
+	}
+	if matched > 0 {
+		fmt.Printf("🔔 Saved searches: %d new matches across %d articles\n", matched, len(items))
+	}
+	return nil
+}
+
+// loadIngestedSince returns the articles of srcs first stored after since,
+// with their ingest times.
+func loadIngestedSince(ctx context.Context, since time.Time, srcs []Source) ([]FeedItem, []time.Time, error) {
+	ids := make([]int64, len(srcs))
+	for i, s := range srcs {
+		ids[i] = int64(s.ID)
+	}
+
+	rows, err := auth.DB.QueryContext(ctx, `
+		SELECT link, title, description, content, published, category, source, ingested_at
+		FROM articles
+		WHERE ingested_at > $1 AND source_id = ANY($2)
+	`, since, pq.Array(ids))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var items []FeedItem
+	var ingested []time.Time
+	for rows.Next() {
+		var it FeedItem
+		var published sql.NullTime
+		var at time.Time
+		if err := rows.Scan(&it.Link, &it.Title, &it.Description, &it.Content, &published,
+			&it.Category, &it.Source, &at); err != nil {
+			return nil, nil, err
+		}
+		it.Published = published.Time
+		items = append(items, it)
+		ingested = append(ingested, at)
+	}
+	return items, ingested, rows.Err()
+}
+
+/* ── handlers ── */
+
+type savedSearchInput struct {
+	Name  *string `json:"name"`
+	Query *string `json:"query"`
+}
+
+// apply copies the non-nil fields of in onto s and validates the result.
+// A query error comes back as a *QueryError so the handler can report its
+// position.
+func (in savedSearchInput) apply(s *SavedSearch) error {
+	if in.Query != nil {
+		s.Query = strings.TrimSpace(*in.Query)
+	}
+	if in.Name != nil {
+		s.Name = strings.TrimSpace(*in.Name)
+	}
+	if s.Name == "" {
+		s.Name = s.Query
+	}
+	if s.Query == "" {
+		return fmt.Errorf("query is required")
+	}
+	if _, err := ParseQuery(s.Query); err != nil {
+		return err
+	}
+	return nil
+}
+
+func savedSearchError(c *gin.Context, err error) {
+	var qerr *QueryError
+	if errors.As(err, &qerr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": qerr.Error(), "pos": qerr.Pos})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// sessionUserID is the logged-in user; routes sit behind auth.RequireLogin.
+func sessionUserID(c *gin.Context) (int, bool) {
+	id, ok := sessions.Default(c).Get("user_id").(int)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	}
+	return id, ok
+}
+
+func loadSavedSearches(userID int) ([]SavedSearch, error) {
+	rows, err := auth.DB.Query(`
+		SELECT s.id, s.name, s.query, s.created_at, s.updated_at, s.last_viewed_at,
+		       (SELECT COUNT(*) FROM saved_search_matches m
+		        WHERE m.search_id = s.id AND m.matched_at > s.last_viewed_at)
+		FROM saved_searches s
+		WHERE s.user_id = $1
+		ORDER BY s.created_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SavedSearch{}
+	for rows.Next() {
+		var s SavedSearch
+		if err := rows.Scan(&s.ID, &s.Name, &s.Query, &s.CreatedAt, &s.UpdatedAt,
+			&s.LastViewedAt, &s.NewCount); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func ListSavedSearchesHandler(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	out, err := loadSavedSearches(userID)
+	if err != nil {
+		log.Printf("❌ List saved searches failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// SavedSearchCountsHandler is the cheap call for badges: new matches per
+// saved search and in total.
+func SavedSearchCountsHandler(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	all, err := loadSavedSearches(userID)
+	if err != nil {
+		log.Printf("❌ Saved search counts failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+
+	type count struct {
+		ID       int `json:"id"`
+		NewCount int `json:"new_count"`
+	}
+	total := 0
+	counts := []count{}
+	for _, s := range all {
+		total += s.NewCount
+		counts = append(counts, count{s.ID, s.NewCount})
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "searches": counts})
+}
+
+func CreateSavedSearchHandler(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var in savedSearchInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	var s SavedSearch
+	if err := in.apply(&s); err != nil {
+		savedSearchError(c, err)
+		return
+	}
+
+	var n int
+	if err := auth.DB.QueryRow(`SELECT COUNT(*) FROM saved_searches WHERE user_id = $1`, userID).Scan(&n); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+	if n >= maxSavedSearches {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d saved searches", maxSavedSearches)})
+		return
+	}
+
+	err := auth.DB.QueryRow(`
+		INSERT INTO saved_searches (user_id, name, query)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at, last_viewed_at
+	`, userID, s.Name, s.Query).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt, &s.LastViewedAt)
+	if err != nil {
+		log.Printf("❌ Create saved search failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not save search"})
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+func UpdateSavedSearchHandler(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search id"})
+		return
+	}
+
+	var in savedSearchInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var s SavedSearch
+	err = auth.DB.QueryRow(`
+		SELECT id, name, query, created_at, updated_at, last_viewed_at
+		FROM saved_searches WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&s.ID, &s.Name, &s.Query, &s.CreatedAt, &s.UpdatedAt, &s.LastViewedAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+
+	oldQuery := s.Query
+	if err := in.apply(&s); err != nil {
+		savedSearchError(c, err)
+		return
+	}
+
+	/* a new query starts tracking afresh: old matches no longer apply */
+	tx, err := auth.DB.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+	defer tx.Rollback()
+
+	if s.Query != oldQuery {
+		if _, err := tx.Exec(`DELETE FROM saved_search_matches WHERE search_id = $1`, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update saved search"})
+			return
+		}
+	}
+	err = tx.QueryRow(`
+		UPDATE saved_searches
+		SET name = $2, query = $3, updated_at = NOW(),
+		    last_run_at = CASE WHEN query = $3 THEN last_run_at ELSE NOW() END
+		WHERE id = $1
+		RETURNING updated_at
+	`, id, s.Name, s.Query).Scan(&s.UpdatedAt)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("❌ Update saved search %d failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update saved search"})
+		return
+	}
+	if s.Query != oldQuery {
+		s.NewCount = 0
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+func DeleteSavedSearchHandler(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search id"})
+		return
+	}
+
+	res, err := auth.DB.Exec(`DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("❌ Delete saved search %d failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete saved search"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted"})
+}
+
+// MarkSavedSearchViewedHandler resets a search's new-match count, e.g. when
+// the user opens its results.
+func MarkSavedSearchViewedHandler(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid saved search id"})
+		return
+	}
+
+	res, err := auth.DB.Exec(`UPDATE saved_searches SET last_viewed_at = NOW() WHERE id = $1 AND user_id = $2`,
+		id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Marked as viewed"})
+}
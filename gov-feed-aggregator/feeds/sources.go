@@ -0,0 +1,374 @@
+package feeds
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"gov-feed-aggregator/auth"
+)
+
+// Source is one row of the `sources` registry table.
+type Source struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	Type         string    `json:"type"`
+	CategoryHint string    `json:"category_hint"`
+	Enabled      bool      `json:"enabled"`
+	PollInterval int       `json:"poll_interval"` // seconds
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const defaultPollInterval = 300
+
+var allowedSourceTypes = map[string]bool{"rss": true, "atom": true, "json": true}
+
+/* ───────────────── DEFAULT SEED LIST ─────────────────────── */
+
+// seeded into an empty `sources` table on first boot
+var defaultSources = []Source{
+	// Defense news and military
+	{Name: "Defense One", URL: "https://www.defenseone.com/rss/all/"},
+	{Name: "Breaking Defense", URL: "https://breakingdefense.com/feed/"},
+	{Name: "Defense News", URL: "https://www.defensenews.com/arc/outboundfeeds/rss/?outputType=xml"},
+	{Name: "RealClearDefense", URL: "https://www.realcleardefense.com/index.xml"},
+	{Name: "U.S. Army", URL: "https://www.army.mil/rss/static/85.xml"},
+	{Name: "RAND National Security", URL: "https://www.rand.org/topics/national-security.xml"},
+	{Name: "U.S. Air Force", URL: "https://www.af.mil/DesktopModules/ArticleCS/RSS.ashx?ContentType=1"},
+	{Name: "Defence IQ: Air Forces", URL: "https://www.defenceiq.com/rss/categories/air-forces-military-aircraft"},
+	{Name: "Defence IQ: Armoured Vehicles", URL: "https://www.defenceiq.com/rss/categories/armoured-vehicles"},
+	{Name: "Defence IQ: Defence Services", URL: "https://www.defenceiq.com/rss/categories/air-land-and-sea-defence-services"},
+	{Name: "Defence IQ: Defence Technology", URL: "https://www.defenceiq.com/rss/categories/defence-technology"},
+	{Name: "Defence IQ: Land Forces", URL: "https://www.defenceiq.com/rss/categories/army-land-forces"},
+	{Name: "Defence IQ: Naval", URL: "https://www.defenceiq.com/rss/categories/naval-maritime-defence"},
+	{Name: "Defence IQ: Cyber", URL: "https://www.defenceiq.com/rss/categories/cyber-defence-and-security"},
+	{Name: "MITRE OVAL News", URL: "https://oval.mitre.org/news/rss/ovalnews.feed.xml"},
+	{Name: "Defence Blog", URL: "https://defence-blog.com/feed/"},
+	{Name: "Army Technology", URL: "https://www.army-technology.com/feed/"},
+	{Name: "Airforce Technology", URL: "https://www.airforce-technology.com/feed/"},
+	{Name: "Naval Technology", URL: "https://www.naval-technology.com/news/feed/"},
+	{Name: "Sociable: Military Technology", URL: "https://sociable.co/military-technology/feed/"},
+	{Name: "Defense One: Technology", URL: "https://www.defenseone.com/rss/technology/"},
+	{Name: "Defense Update", URL: "https://defense-update.com/feed"},
+	{Name: "Breaking Defense (full)", URL: "https://breakingdefense.com/full-rss-feed/?v=2"},
+
+	// Government Agencies
+	{Name: "DoD: Releases", URL: "https://www.defense.gov/DesktopModules/ArticleCS/RSS.ashx?ContentType=800&Site=945&max=10"},
+	{Name: "DoD: News", URL: "https://www.defense.gov/DesktopModules/ArticleCS/RSS.ashx?max=10&ContentType=1&Site=945"},
+	{Name: "DoD: Contracts", URL: "https://www.defense.gov/DesktopModules/ArticleCS/RSS.ashx?ContentType=400&Site=945&max=10"},
+	{Name: "NIH Extramural Nexus", URL: "https://nexus.od.nih.gov/all/feed/"},
+	{Name: "Rural Health Info: Grants", URL: "https://www.ruralhealthinfo.org/rss/funding/types/grants-and-contracts.xml"},
+
+	// Grants
+	{Name: "NIH All About Grants", URL: "https://grants.nih.gov/podcasts/All_About_Grants/AAG_Feed.xml"},
+	{Name: "NSF Events", URL: "https://www.nsf.gov/rss/rss_www_events.xml"},
+	{Name: "NSF Funding Announcements", URL: "https://www.nsf.gov/rss/rss_www_funding_pgm_annc_inf.xml"},
+	{Name: "NSF Upcoming Funding", URL: "https://www.nsf.gov/news/mmg/rss/rss_www_funding_upcoming.xml"},
+	{Name: "NSF News", URL: "https://www.nsf.gov/rss/rss_www_news.xml"},
+
+	// Ally / international defense
+	{Name: "UK Ministry of Defence", URL: "https://www.gov.uk/government/organisations/ministry-of-defence.atom", Type: "atom"},
+	{Name: "NATO Watch", URL: "https://natowatch.org/news.xml"},
+	{Name: "UK Defence Journal", URL: "https://ukdefencejournal.org.uk/feed/"},
+	{Name: "Russian Defense Policy", URL: "https://russiandefpolicy.com/feed/"},
+}
+
+/* ───────────────── IN‑MEMORY REGISTRY ────────────────────── */
+
+var (
+	registry   []Source
+	registryMu sync.RWMutex
+)
+
+// InitSources creates the `sources` table if needed, seeds it on first boot
+// and loads the registry into memory.
+func InitSources() error {
+	_, err := auth.DB.Exec(`
+		CREATE TABLE IF NOT EXISTS sources (
+			id            SERIAL PRIMARY KEY,
+			name          TEXT NOT NULL,
+			url           TEXT NOT NULL UNIQUE,
+			type          TEXT NOT NULL DEFAULT 'rss',
+			category_hint TEXT NOT NULL DEFAULT '',
+			enabled       BOOLEAN NOT NULL DEFAULT TRUE,
+			poll_interval INTEGER NOT NULL DEFAULT 300,
+			created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("create sources table: %w", err)
+	}
+
+	_, err = auth.DB.Exec(`
+		ALTER TABLE sources
+			ADD COLUMN IF NOT EXISTS etag          TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS last_modified TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("migrate sources table: %w", err)
+	}
+
+	var count int
+	if err := auth.DB.QueryRow(`SELECT COUNT(*) FROM sources`).Scan(&count); err != nil {
+		return fmt.Errorf("count sources: %w", err)
+	}
+	if count == 0 {
+		for _, s := range defaultSources {
+			if s.Type == "" {
+				s.Type = "rss"
+			}
+			_, err := auth.DB.Exec(`
+				INSERT INTO sources (name, url, type, category_hint, enabled, poll_interval)
+				VALUES ($1, $2, $3, $4, TRUE, $5)
+				ON CONFLICT (url) DO NOTHING
+			`, s.Name, s.URL, s.Type, s.CategoryHint, defaultPollInterval)
+			if err != nil {
+				return fmt.Errorf("seed source %s: %w", s.URL, err)
+			}
+		}
+		log.Printf("🌱 Seeded %d default sources", len(defaultSources))
+	}
+
+	return reloadSources()
+}
+
+// reloadSources refreshes the in‑memory registry from the DB.
+func reloadSources() error {
+	rows, err := auth.DB.Query(`
+		SELECT id, name, url, type, category_hint, enabled, poll_interval, created_at, updated_at,
+		       etag, last_modified
+		FROM sources
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var loaded []Source
+	loadedValidators := make(map[string]cacheValidators)
+	for rows.Next() {
+		var s Source
+		var v cacheValidators
+		if err := rows.Scan(&s.ID, &s.Name, &s.URL, &s.Type, &s.CategoryHint,
+			&s.Enabled, &s.PollInterval, &s.CreatedAt, &s.UpdatedAt,
+			&v.ETag, &v.LastModified); err != nil {
+			return err
+		}
+		loaded = append(loaded, s)
+		loadedValidators[s.URL] = v
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	registryMu.Lock()
+	registry = loaded
+	registryMu.Unlock()
+
+	validatorsMu.Lock()
+	validators = loadedValidators
+	validatorsMu.Unlock()
+	return nil
+}
+
+// enabledSources returns a snapshot of every enabled source.
+func enabledSources() []Source {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	out := make([]Source, 0, len(registry))
+	for _, s := range registry {
+		if s.Enabled {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+/* ───────────────── ADMIN HANDLERS ────────────────────────── */
+
+type sourceInput struct {
+	Name         *string `json:"name"`
+	URL          *string `json:"url"`
+	Type         *string `json:"type"`
+	CategoryHint *string `json:"category_hint"`
+	Enabled      *bool   `json:"enabled"`
+	PollInterval *int    `json:"poll_interval"`
+}
+
+// apply copies the non-nil fields of in onto s and validates the result.
+func (in sourceInput) apply(s *Source) error {
+	if in.Name != nil {
+		s.Name = strings.TrimSpace(*in.Name)
+	}
+	if in.URL != nil {
+		s.URL = strings.TrimSpace(*in.URL)
+	}
+	if in.Type != nil {
+		s.Type = strings.ToLower(strings.TrimSpace(*in.Type))
+	}
+	if in.CategoryHint != nil {
+		s.CategoryHint = strings.TrimSpace(*in.CategoryHint)
+	}
+	if in.Enabled != nil {
+		s.Enabled = *in.Enabled
+	}
+	if in.PollInterval != nil {
+		s.PollInterval = *in.PollInterval
+	}
+
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if !allowedSourceTypes[s.Type] {
+		return fmt.Errorf("type must be one of rss, atom, json")
+	}
+	if s.PollInterval < 60 {
+		return fmt.Errorf("poll_interval must be at least 60 seconds")
+	}
+	return nil
+}
+
+func ListSourcesHandler(c *gin.Context) {
+	registryMu.RLock()
+	out := make([]Source, len(registry))
+	copy(out, registry)
+	registryMu.RUnlock()
+
+	c.JSON(http.StatusOK, out)
+}
+
+func CreateSourceHandler(c *gin.Context) {
+	var in sourceInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	s := Source{Type: "rss", Enabled: true, PollInterval: defaultPollInterval}
+	if err := in.apply(&s); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := auth.DB.QueryRow(`
+		INSERT INTO sources (name, url, type, category_hint, enabled, poll_interval)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, s.Name, s.URL, s.Type, s.CategoryHint, s.Enabled, s.PollInterval).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A source with this URL already exists"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Create source failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create source"})
+		return
+	}
+
+	if err := reloadSources(); err != nil {
+		log.Printf("⚠️ Source registry reload failed: %v", err)
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+func UpdateSourceHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source id"})
+		return
+	}
+
+	var in sourceInput
+	if err := c.BindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var s Source
+	err = auth.DB.QueryRow(`
+		SELECT id, name, url, type, category_hint, enabled, poll_interval, created_at, updated_at
+		FROM sources WHERE id = $1
+	`, id).Scan(&s.ID, &s.Name, &s.URL, &s.Type, &s.CategoryHint,
+		&s.Enabled, &s.PollInterval, &s.CreatedAt, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "DB error"})
+		return
+	}
+
+	if err := in.apply(&s); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = auth.DB.QueryRow(`
+		UPDATE sources
+		SET name = $2, url = $3, type = $4, category_hint = $5, enabled = $6, poll_interval = $7, updated_at = NOW(),
+		    etag = CASE WHEN url = $3 THEN etag ELSE '' END,
+		    last_modified = CASE WHEN url = $3 THEN last_modified ELSE '' END
+		WHERE id = $1
+		RETURNING updated_at
+	`, s.ID, s.Name, s.URL, s.Type, s.CategoryHint, s.Enabled, s.PollInterval).Scan(&s.UpdatedAt)
+	if isUniqueViolation(err) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another source already uses this URL"})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Update source %d failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update source"})
+		return
+	}
+
+	if err := reloadSources(); err != nil {
+		log.Printf("⚠️ Source registry reload failed: %v", err)
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate
+// (sources.url is UNIQUE).
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func DeleteSourceHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source id"})
+		return
+	}
+
+	res, err := auth.DB.Exec(`DELETE FROM sources WHERE id = $1`, id)
+	if err != nil {
+		log.Printf("❌ Delete source %d failed: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete source"})
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source not found"})
+		return
+	}
+
+	if err := reloadSources(); err != nil {
+		log.Printf("⚠️ Source registry reload failed: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Source deleted"})
+}
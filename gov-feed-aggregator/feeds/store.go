@@ -0,0 +1,161 @@
+package feeds
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── ARTICLE STORE ─────────────────────────── */
+
+// InitStore makes sure the `articles` table can hold complete FeedItems.
+// Older deployments only had (link, title), so the extra columns are added
+// in place.
+func InitStore() error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS articles (
+			link  TEXT PRIMARY KEY,
+			title TEXT NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS articles_link_idx ON articles (link)`,
+		`ALTER TABLE articles ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE articles ADD COLUMN IF NOT EXISTS published   TIMESTAMPTZ`,
+		`ALTER TABLE articles ADD COLUMN IF NOT EXISTS category    TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE articles ADD COLUMN IF NOT EXISTS source      TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE articles ADD COLUMN IF NOT EXISTS source_id   INTEGER`,
+		`ALTER TABLE articles ADD COLUMN IF NOT EXISTS ingested_at TIMESTAMPTZ NOT NULL DEFAULT NOW()`,
+		`ALTER TABLE articles ADD COLUMN IF NOT EXISTS content     TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS articles_source_id_idx ON articles (source_id)`,
+		`CREATE INDEX IF NOT EXISTS articles_published_idx ON articles (published DESC)`,
+	}
+	if searchBackend == "postgres" {
+		stmts = append(stmts, ftsSchema...)
+	}
+	for _, stmt := range stmts {
+		if _, err := auth.DB.Exec(stmt); err != nil {
+			return fmt.Errorf("migrate articles: %w", err)
+		}
+	}
+	return nil
+}
+
+// upsertArticles writes every item of one source, refreshing rows that
+// were already stored (e.g. by the old link/title-only insert).
+func upsertArticles(ctx context.Context, src Source, items []FeedItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	tx, err := auth.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO articles (link, title, description, content, published, category, source, source_id, ingested_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (link) DO UPDATE SET
+			title       = EXCLUDED.title,
+			description = EXCLUDED.description,
+			content     = EXCLUDED.content,
+			published   = COALESCE(EXCLUDED.published, articles.published),
+			category    = EXCLUDED.category,
+			source      = EXCLUDED.source,
+			source_id   = EXCLUDED.source_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, it := range items {
+		if it.Link == "" {
+			continue
+		}
+		published := sql.NullTime{Time: it.Published, Valid: !it.Published.IsZero()}
+		if _, err := stmt.ExecContext(ctx, it.Link, it.Title, it.Description, it.Content, published,
+			it.Category, it.Source, src.ID); err != nil {
+			return fmt.Errorf("upsert %s: %w", it.Link, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// loadStoredArticles returns every stored article belonging to srcs,
+// newest first.
+func loadStoredArticles(ctx context.Context, srcs []Source) ([]FeedItem, error) {
+	ids := make([]int64, len(srcs))
+	for i, s := range srcs {
+		ids[i] = int64(s.ID)
+	}
+
+	rows, err := auth.DB.QueryContext(ctx, `
+		SELECT link, title, description, content, published, category, source
+		FROM articles
+		WHERE source_id = ANY($1)
+		ORDER BY published DESC NULLS LAST
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("load articles: %w", err)
+	}
+	defer rows.Close()
+
+	var items []FeedItem
+	for rows.Next() {
+		var it FeedItem
+		var published sql.NullTime
+		if err := rows.Scan(&it.Link, &it.Title, &it.Description, &it.Content, &published,
+			&it.Category, &it.Source); err != nil {
+			return nil, err
+		}
+		it.Published = published.Time
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// SavedArticles returns the stored articles userID saved, newest first,
+// narrowed by filters. Only the saved rows are read, however large the
+// store grows.
+func SavedArticles(ctx context.Context, userID int, filters SearchFilters) (*SearchResult, error) {
+	start := time.Now()
+	_, meta := storedCorpus()
+
+	rows, err := auth.DB.QueryContext(ctx, `
+		SELECT a.link, a.title, a.description, a.content, a.published, a.category, a.source
+		FROM feedback f
+		JOIN articles a ON a.link = f.article_id
+		WHERE f.user_id = $1 AND f.action = 'save'
+		ORDER BY a.published DESC NULLS LAST
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load saved articles: %w", err)
+	}
+	defer rows.Close()
+
+	filter := filters.node()
+	items := []FeedItem{}
+	for rows.Next() {
+		var it FeedItem
+		var published sql.NullTime
+		if err := rows.Scan(&it.Link, &it.Title, &it.Description, &it.Content, &published,
+			&it.Category, &it.Source); err != nil {
+			return nil, err
+		}
+		it.Published = published.Time
+		if filter == nil || filter.Match(newMatchDoc(&it, false)) {
+			items = append(items, it)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	meta.ElapsedMs = time.Since(start).Milliseconds()
+	return &SearchResult{Items: items, Meta: meta}, nil
+}
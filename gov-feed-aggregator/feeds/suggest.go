@@ -0,0 +1,277 @@
+package feeds
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"gov-feed-aggregator/auth"
+)
+
+/* ───────────────── AUTOCOMPLETE ──────────────────────────── */
+
+// /suggest completes a partial query from three places: 1–3 word n-grams of
+// indexed titles, queries several users have saved, and the caller's own
+// topics. Everything but the topics lives in one sorted slice that is
+// rebuilt after ingestion, so a lookup is a binary search plus a short scan
+// and never touches the DB.
+
+const (
+	defaultSuggestions = 8
+	maxSuggestions     = 20
+	suggestMaxWords    = 3
+	suggestMinDF       = 2   // multi-word n-grams seen in fewer titles are noise
+	suggestSavedMin    = 2   // a saved query is popular once this many users keep it
+	suggestAffinity    = 1.5 // weight of the caller's topic scores
+)
+
+// Suggestion is one completion.
+type Suggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"` // title | saved | topic
+}
+
+type suggestEntry struct {
+	text string
+	kind string
+	freq int // titles containing the n-gram, or users who saved the query
+}
+
+type suggester struct {
+	mu      sync.RWMutex
+	entries []suggestEntry // sorted by text
+	gen     uint64         // searchIndex.gen the entries were built from
+	built   bool
+}
+
+var suggestions = &suggester{}
+
+// RebuildSuggestions refreshes the completion list if the index changed
+// since the last build. The ingester calls it after every pass.
+func RebuildSuggestions(ctx context.Context) {
+	searchIndex.mu.RLock()
+	gen := searchIndex.gen
+	searchIndex.mu.RUnlock()
+
+	suggestions.mu.RLock()
+	fresh := suggestions.built && suggestions.gen == gen
+	suggestions.mu.RUnlock()
+	if fresh {
+		return
+	}
+
+	start := time.Now()
+	df := titleNgrams(enabledSourceIDs())
+
+	entries := make([]suggestEntry, 0, len(df))
+	for text, n := range df {
+		if n >= suggestMinDF || !strings.Contains(text, " ") {
+			entries = append(entries, suggestEntry{text: text, kind: "title", freq: n})
+		}
+	}
+	saved, err := popularSavedQueries(ctx)
+	if err != nil {
+		log.Printf("⚠️ Could not load saved queries for suggestions: %v", err)
+	}
+	entries = append(entries, saved...)
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].text != entries[j].text {
+			return entries[i].text < entries[j].text
+		}
+		return entries[i].kind < entries[j].kind
+	})
+
+	suggestions.mu.Lock()
+	suggestions.entries, suggestions.gen, suggestions.built = entries, gen, true
+	suggestions.mu.Unlock()
+	log.Printf("💡 Suggestions rebuilt: %d entries in %v", len(entries), time.Since(start).Round(time.Millisecond))
+}
+
+func enabledSourceIDs() map[int]bool {
+	ids := make(map[int]bool)
+	for _, s := range enabledSources() {
+		ids[s.ID] = true
+	}
+	return ids
+}
+
+// titleNgrams counts, per n-gram, the live titles it appears in. N-grams
+// may not start or end on a stopword ("of the", "missile for").
+func titleNgrams(sourceIDs map[int]bool) map[string]int {
+	searchIndex.mu.RLock()
+	defer searchIndex.mu.RUnlock()
+
+	df := make(map[string]int)
+	for _, doc := range searchIndex.Docs {
+		if doc.Deleted || !sourceIDs[doc.SourceID] {
+			continue
+		}
+		words := suggestWords(doc.Item.Title)
+		seen := make(map[string]bool)
+		for i := range words {
+			if !completable(words[i]) {
+				continue
+			}
+			for n := 1; n <= suggestMaxWords && i+n <= len(words); n++ {
+				if !completable(words[i+n-1]) {
+					continue
+				}
+				g := strings.Join(words[i:i+n], " ")
+				if !seen[g] {
+					seen[g] = true
+					df[g]++
+				}
+			}
+		}
+	}
+	return df
+}
+
+// suggestWords splits a title into lower-case words the way users type
+// them: "F-35A's" stays "f-35a", unlike the analyzer's [f 35a s].
+func suggestWords(s string) []string {
+	var out []string
+	for _, w := range strings.Fields(strings.ToLower(stripHTML(s))) {
+		w = strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		w = strings.TrimSuffix(strings.TrimSuffix(w, "'s"), "’s")
+		if w != "" {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// completable is whether a word may start or end a suggestion.
+func completable(w string) bool {
+	return !stopwords[w] && (len(w) > 2 || strings.ContainsAny(w, "0123456789"))
+}
+
+func popularSavedQueries(ctx context.Context) ([]suggestEntry, error) {
+	rows, err := auth.DB.QueryContext(ctx, `
+		SELECT lower(query), COUNT(DISTINCT user_id)
+		FROM saved_searches
+		GROUP BY lower(query)
+		HAVING COUNT(DISTINCT user_id) >= $1
+	`, suggestSavedMin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []suggestEntry
+	for rows.Next() {
+		var e suggestEntry
+		if err := rows.Scan(&e.text, &e.freq); err != nil {
+			return nil, err
+		}
+		e.text = strings.Join(strings.Fields(e.text), " ")
+		e.kind = "saved"
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+/* ── lookup ── */
+
+// suggest returns up to limit completions of prefix, ranked by frequency
+// plus the caller's affinity for the words in them.
+func (s *suggester) suggest(prefix string, topics map[string]float64, limit int) []Suggestion {
+	type ranked struct {
+		Suggestion
+		score float64
+	}
+	best := make(map[string]ranked)
+	consider := func(text, kind string, score float64) {
+		if r, ok := best[text]; !ok || score > r.score {
+			best[text] = ranked{Suggestion{text, kind}, score}
+		}
+	}
+	affinity := func(text string) float64 {
+		sum := 0.0
+		for _, w := range strings.Fields(text) {
+			sum += topics[w]
+		}
+		return suggestAffinity * math.Log1p(sum)
+	}
+
+	s.mu.RLock()
+	i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].text >= prefix })
+	for ; i < len(s.entries) && strings.HasPrefix(s.entries[i].text, prefix); i++ {
+		e := s.entries[i]
+		score := math.Log1p(float64(e.freq))
+		if e.kind == "saved" {
+			score = 1 + 2*score
+		}
+		consider(e.text, e.kind, score+affinity(e.text))
+	}
+	s.mu.RUnlock()
+
+	for topic := range topics {
+		if strings.HasPrefix(topic, prefix) {
+			consider(topic, "topic", 1+affinity(topic))
+		}
+	}
+
+	out := make([]ranked, 0, len(best))
+	for _, r := range best {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].score != out[j].score {
+			return out[i].score > out[j].score
+		}
+		return out[i].Text < out[j].Text
+	})
+
+	res := make([]Suggestion, 0, limit)
+	for _, r := range out {
+		if len(res) == limit {
+			break
+		}
+		res = append(res, r.Suggestion)
+	}
+	return res
+}
+
+/* ── handler ── */
+
+// SuggestHandler serves GET /suggest?q=&limit=. Login is optional; with a
+// session the caller's topics are suggested and boost related completions.
+func SuggestHandler(c *gin.Context) {
+	prefix := strings.Join(strings.Fields(strings.ToLower(c.Query("q"))), " ")
+	if strings.HasSuffix(c.Query("q"), " ") && prefix != "" {
+		prefix += " " // "hypersonic " completes the next word only
+	}
+
+	limit := defaultSuggestions
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(n, maxSuggestions)
+	}
+
+	if prefix == "" {
+		c.JSON(http.StatusOK, gin.H{"q": c.Query("q"), "suggestions": []Suggestion{}})
+		return
+	}
+
+	var topics map[string]float64
+	if userID, ok := sessions.Default(c).Get("user_id").(int); ok {
+		topics = userTopics(c.Request.Context(), userID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"q": c.Query("q"), "suggestions": suggestions.suggest(prefix, topics, limit)})
+}
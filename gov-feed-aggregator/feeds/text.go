@@ -0,0 +1,117 @@
+package feeds
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+/* ───────────────── TEXT HELPERS ──────────────────────────── */
+
+var (
+	htmlTagRe    = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]*>`)
+	whitespaceRe = regexp.MustCompile(`\s+`)
+)
+
+// stripHTML turns a feed's HTML fragment into plain, single-spaced text.
+func stripHTML(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(whitespaceRe.ReplaceAllString(s, " "))
+}
+
+/* ───────────────── ANALYSIS ──────────────────────────────── */
+
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "in": true, "into": true,
+	"is": true, "it": true, "its": true, "of": true, "on": true, "or": true, "that": true,
+	"the": true, "their": true, "this": true, "to": true, "was": true, "were": true,
+	"will": true, "with": true, "about": true, "they": true, "your": true,
+}
+
+// tokenize lower-cases s and splits it on anything that isn't a letter or
+// digit, so "F-35A's" becomes [f 35a s].
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// analyze is tokenize + stem. Stopwords are kept so phrases still line up;
+// the index drops them when building postings.
+func analyze(s string) []string {
+	toks := tokenize(s)
+	for i, t := range toks {
+		toks[i] = stem(t)
+	}
+	return toks
+}
+
+// stem is a light English suffix stripper: enough to fold plurals and
+// -ing/-ed/-ly forms ("contracts", "contracting" → "contract") without
+// mangling acronyms and designators. Short bases are left alone, so
+// "apply", "early" and "seeing" don't collapse into other words.
+func stem(w string) string {
+	if len(w) < 4 || !isAlpha(w) || unstemmed[w] {
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		w = w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "sses"):
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"), strings.HasSuffix(w, "us"), strings.HasSuffix(w, "is"):
+	case strings.HasSuffix(w, "s"):
+		w = w[:len(w)-1]
+	}
+
+	for _, suf := range []string{"ingly", "edly", "ing", "ed", "ly"} {
+		if !strings.HasSuffix(w, suf) {
+			continue
+		}
+		base := w[:len(w)-len(suf)]
+		if len(base) < 3 || !strings.ContainsAny(base, "aeiouy") {
+			break
+		}
+		if suf == "ly" {
+			// "early", "family", "supply": too short, or not an adverb
+			if len(base) < 4 || isVowel(base[len(base)-1]) || base[len(base)-1] == 'p' {
+				break
+			}
+			w = base
+			break
+		}
+		// "planned" → "plann" → "plan", but "seeing" stays "see"
+		if n := len(base); base[n-1] == base[n-2] && !isVowel(base[n-1]) &&
+			!strings.ContainsRune("lsz", rune(base[n-1])) {
+			base = base[:n-1]
+		}
+		w = base
+		break
+	}
+	return w
+}
+
+// unstemmed are words whose endings look like a suffix but aren't one.
+var unstemmed = map[string]bool{
+	"news": true, "series": true, "species": true,
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package feeds
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		// plurals and verb forms fold together
+		{"contracts", "contract"},
+		{"contracting", "contract"},
+		{"contracted", "contract"},
+		{"drones", "drone"},
+		{"policies", "policy"},
+		{"classes", "class"},
+		{"planned", "plan"},
+		{"stopped", "stop"},
+		{"running", "run"},
+		{"quickly", "quick"},
+		{"reportedly", "report"},
+
+		// endings that only look like suffixes
+		{"news", "news"},
+		{"series", "series"},
+		{"species", "species"},
+		{"status", "status"},
+		{"analysis", "analysis"},
+		{"seeing", "see"},
+		{"feeling", "feel"},
+		{"fully", "fully"},
+		{"supply", "supply"},
+		{"apply", "apply"},
+		{"reply", "reply"},
+		{"early", "early"},
+		{"family", "family"},
+		{"only", "only"},
+
+		// acronyms, designators and short words are left alone
+		{"f-35", "f-35"},
+		{"35a", "35a"},
+		{"uas", "uas"},
+		{"bus", "bus"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.word); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestStemKeepsWordsApart(t *testing.T) {
+	pairs := [][2]string{
+		{"news", "new"},
+		{"seeing", "se"},
+		{"supply", "sup"},
+		{"reply", "rep"},
+		{"early", "ear"},
+		{"series", "sery"},
+	}
+	for _, p := range pairs {
+		if stem(p[0]) == stem(p[1]) {
+			t.Errorf("stem(%q) == stem(%q) == %q", p[0], p[1], stem(p[0]))
+		}
+	}
+}
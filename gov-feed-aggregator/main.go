@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -19,18 +24,70 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	feeds.LoadConfig()
+
 	err = auth.InitDB()
 	if err != nil {
 		log.Fatal("❌ Failed to connect to DB: ", err)
 	}
 
+	err = auth.InitExpansionCache()
+	if err != nil {
+		log.Fatal("❌ Failed to create query expansion cache: ", err)
+	}
+
+	err = auth.InitTopicDecay()
+	if err != nil {
+		log.Fatal("❌ Failed to migrate topic scores: ", err)
+	}
+
+	err = feeds.InitSources()
+	if err != nil {
+		log.Fatal("❌ Failed to load source registry: ", err)
+	}
+
+	err = feeds.InitStore()
+	if err != nil {
+		log.Fatal("❌ Failed to migrate article store: ", err)
+	}
+
+	err = feeds.InitHealth()
+	if err != nil {
+		log.Fatal("❌ Failed to load source health: ", err)
+	}
+
+	err = feeds.LoadExpansions()
+	if err != nil {
+		log.Fatal("❌ Failed to load query expansions: ", err)
+	}
+
+	err = feeds.InitIndex()
+	if err != nil {
+		log.Fatal("❌ Failed to build search index: ", err)
+	}
+
+	err = feeds.InitSavedSearches()
+	if err != nil {
+		log.Fatal("❌ Failed to create saved searches: ", err)
+	}
+
+	err = feeds.InitQueryLog()
+	if err != nil {
+		log.Fatal("❌ Failed to create query log: ", err)
+	}
+
+	feeds.RebuildSuggestions(context.Background())
+
+	go feeds.StartIngester(context.Background())
+	go feeds.StartSavedSearchScheduler(context.Background())
+
 	router := gin.Default()
 
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -49,73 +106,156 @@ func main() {
 	router.Use(sessions.Sessions("govfeed_session", store))
 	
 	router.GET("/feed", func(c *gin.Context) {
+		started := time.Now()
 		query := c.Query("query")
-		filter := c.Query("filter")
+
+		// reaction filters by the user's feedback (like/dislike/save/hide);
+		// "filter" is its old name, still accepted from older clients
+		reaction := c.Query("reaction")
+		if reaction == "" {
+			reaction = c.Query("filter")
+		}
+		if reaction != "" && !feedbackActions[reaction] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid reaction %q", reaction)})
+			return
+		}
 	
 		session := sessions.Default(c)
 		userID := session.Get("user_id")
+
+		opts := feeds.SearchOptions{}
+		if id, ok := userID.(int); ok {
+			opts.UserID = id // 🎯 personalized relevance
+		}
+
+		page, err := feeds.ParsePageOptions(c.Query("limit"), c.Query("cursor"), c.Query("sort"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		opts.Filters, err = feeds.ParseFilters(c.Query("from"), c.Query("to"),
+			c.QueryArray("category"), c.QueryArray("source"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// ⏱️ deadline (e.g. 1500ms) bounds the whole search; FEED_SEARCH_TIMEOUT
+		// is the default and the cap
+		var deadline time.Duration
+		if raw := c.Query("deadline"); raw != "" {
+			if deadline, err = parseDeadline(raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		ctx, cancel := feeds.SearchContext(c.Request.Context(), deadline)
+		defer cancel()
 	
 		// ✅ Handle "Saved" Tab First
-		if filter == "save" {
-			if userID == nil {
+		if reaction == "save" && query == "" {
+			id, ok := userID.(int)
+			if !ok {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
 				return
 			}
 	
-			rows, err := auth.DB.Query(`SELECT article_id FROM feedback WHERE user_id = $1 AND action = 'save'`, userID)
+			all, err := feeds.SavedArticles(ctx, id, opts.Filters)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve saved articles"})
+				log.Printf("❌ Failed to retrieve saved articles: %v", err)
+				searchFailed(ctx, c, "Failed to retrieve saved articles")
 				return
 			}
-			defer rows.Close()
 	
-			savedLinks := map[string]bool{}
-			for rows.Next() {
-				var articleID string
-				rows.Scan(&articleID)
-				savedLinks[articleID] = true
-			}
+			all.Paginate(page)
+			c.JSON(http.StatusOK, all)
+			return
+		}
 	
-			allItems, err := feeds.DeepSearch("") // 👈 grabs everything
+		// 🏠 No query or filter: logged-in users get their For You feed
+		if query == "" && opts.Filters.IsZero() {
+			id, ok := userID.(int)
+			if !ok || reaction != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "query or a filter (from, to, category, source) is required"})
+				return
+			}
+			res, err := feeds.ForYou(ctx, id)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				searchFailed(ctx, c, err.Error())
 				return
 			}
-	
-			filtered := []feeds.FeedItem{}
-			for _, item := range allItems {
-				if savedLinks[item.Link] {
-					filtered = append(filtered, item)
+			res.Paginate(page)
+			c.JSON(http.StatusOK, res)
+			return
+		}
+
+		// 🔮 expand=true ORs related keywords into the query's plain text;
+		// exclusions and field filters still apply. OpenAI (and its cache)
+		// is for logged-in users, like /expand-query; anonymous searches get
+		// the local extractor.
+		if c.Query("expand") == "true" && query != "" {
+			if text := feeds.ExpansionText(query); text != "" {
+				keywords, source := auth.ExpandQueryLocal(text), "local"
+				if _, ok := userID.(int); ok {
+					keywords, source = auth.ExpandQuery(ctx, text)
 				}
+				opts.Expansions = keywords
+				log.Printf("🔎 Expanded [%s] via %s → %v", text, source, keywords)
 			}
-	
-			c.JSON(http.StatusOK, filtered)
-			return
+		}
+		expanded := len(opts.Expansions) > 0
+
+		// 📊 query log for the admin search report: literal matches only
+		// (fuzzy "did you mean" hits don't count), and failures too
+		deepFallback := false
+		logQuery := func(res *feeds.SearchResult, err error) {
+			entry := feeds.QueryLogEntry{Query: query, Latency: time.Since(started),
+				DeepFallback: deepFallback, Expanded: expanded}
+			if err != nil {
+				entry.Error = err.Error()
+			} else {
+				entry.Results = res.LiteralMatches()
+			}
+			if id, ok := userID.(int); ok {
+				entry.UserID = &id
+			}
+			feeds.LogQuery(entry)
 		}
 	
-		// 🔒 Fallback for general feed requests
-		if query == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter is required"})
+		res, err := feeds.QuickSearch(ctx, query, opts)
+		var qerr *feeds.QueryError
+		if errors.As(err, &qerr) {
+			logQuery(nil, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": qerr.Error(), "pos": qerr.Pos})
 			return
 		}
-	
-		items, err := feeds.QuickSearch(query)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			logQuery(nil, err)
+			searchFailed(ctx, c, err.Error())
 			return
 		}
 		
 		/* 🔁 Fallback to deep search if no title matches */
-		if len(items) == 0 {
-			items, err = feeds.DeepSearch(query)
+		deepFallback = len(res.Items) == 0 && query != ""
+		if deepFallback {
+			deep, err := feeds.DeepSearch(ctx, query, opts)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				logQuery(nil, err)
+				searchFailed(ctx, c, err.Error())
 				return
 			}
+			deep.Meta.ElapsedMs += res.Meta.ElapsedMs
+			res = deep
+		}
+		if expanded {
+			res.DidYouMean = "" // spelled against the expanded query, not what the user typed
 		}
 
 		if userID == nil {
-			c.JSON(http.StatusOK, items)
+			logQuery(res, nil)
+			res.Paginate(page)
+			c.JSON(http.StatusOK, res)
 			return
 		}
 	
@@ -131,15 +271,18 @@ func main() {
 		}
 	
 		filtered := []feeds.FeedItem{}
-		for _, item := range items {
+		for _, item := range res.Items {
 			action := feedbackMap[item.Link]
-			if filter != "" && action != filter {
+			if reaction != "" && action != reaction {
 				continue
 			}
 			filtered = append(filtered, item)
 		}
 	
-		c.JSON(http.StatusOK, filtered)
+		res.Items = filtered
+		logQuery(res, nil)
+		res.Paginate(page)
+		c.JSON(http.StatusOK, res)
 	})
 	
 			
@@ -155,8 +298,29 @@ func main() {
 	router.GET("/feedback", auth.GetUserFeedbackHandler)
 	router.GET("/boost-topics", auth.GetTopTopicsHandler)
 	router.GET("/user-topics", auth.RequireLogin(), auth.GetUserTopics)
+
+	router.GET("/suggest", feeds.SuggestHandler)
+	router.GET("/related", feeds.RelatedHandler)
+
+	saved := router.Group("/saved-searches", auth.RequireLogin())
+	saved.GET("", feeds.ListSavedSearchesHandler)
+	saved.GET("/new", feeds.SavedSearchCountsHandler)
+	saved.POST("", feeds.CreateSavedSearchHandler)
+	saved.PUT("/:id", feeds.UpdateSavedSearchHandler)
+	saved.DELETE("/:id", feeds.DeleteSavedSearchHandler)
+	saved.POST("/:id/viewed", feeds.MarkSavedSearchViewedHandler)
 	router.POST("/onboarding", auth.OnboardingHandler)
 	router.POST("/summarize", auth.SummarizeHandler)
+	router.POST("/expand-query", auth.QueryExpansionHandler)
+
+	admin := router.Group("/admin", auth.RequireAdmin())
+	admin.GET("/sources", feeds.ListSourcesHandler)
+	admin.POST("/sources", feeds.CreateSourceHandler)
+	admin.PUT("/sources/:id", feeds.UpdateSourceHandler)
+	admin.DELETE("/sources/:id", feeds.DeleteSourceHandler)
+	admin.GET("/search-report", feeds.SearchReportHandler)
+
+	router.GET("/sources/health", auth.RequireAdmin(), feeds.SourceHealthHandler)
 	
 
 	router.GET("/federal", func(c *gin.Context) {
@@ -188,3 +352,26 @@ func main() {
 
 	router.Run(":8080")
 }
+
+var feedbackActions = map[string]bool{"like": true, "dislike": true, "save": true, "hide": true}
+
+// parseDeadline accepts a Go duration ("1500ms", "2s") or bare milliseconds.
+func parseDeadline(raw string) (time.Duration, error) {
+	if ms, err := strconv.Atoi(raw); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid deadline %q", raw)
+}
+
+// searchFailed answers a failed search: 504 when it ran past its deadline,
+// 500 with msg otherwise.
+func searchFailed(ctx context.Context, c *gin.Context, msg string) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "search did not finish within its deadline"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+}
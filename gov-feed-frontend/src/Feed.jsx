@@ -39,6 +39,16 @@ export default function Feed() {
 
   const [subQuery, setSubQuery] = useState('');
 
+  // /feed is paged; nextCursor fetches the page after the last one we have
+  const [nextCursor, setNextCursor] = useState(null);
+  const [lastFeedQuery, setLastFeedQuery] = useState(null);
+  const [isLoadingMore, setIsLoadingMore] = useState(false);
+  const [didYouMean, setDidYouMean] = useState(null);
+  // the "For You" home feed arrives ranked; don't re-sort it by date
+  const [isForYou, setIsForYou] = useState(false);
+  // related-keyword expansion is opt-in: uncached queries wait on OpenAI
+  const [expand, setExpand] = useState(false);
+
 
 
   useEffect(() => {
@@ -77,12 +87,43 @@ export default function Feed() {
         setShowContinueOptions(true);
       } else if (visibleCount < feedItems.length) {
         setVisibleCount(v => v + 10);
+      } else if (nextCursor) {
+        loadMore();
       }
     };
   
     window.addEventListener('scroll', handleScroll);
     return () => window.removeEventListener('scroll', handleScroll);
-  }, [hasContinued, visibleCount, feedItems]);
+  }, [hasContinued, visibleCount, feedItems, nextCursor, isLoadingMore]);
+
+  // 📜 Next page of the current search (same query/filter, server-side cursor)
+  const loadMore = async () => {
+    if (isLoadingMore || !nextCursor || !lastFeedQuery) return;
+    setIsLoadingMore(true);
+    try {
+      const feedURL = new URL('http://localhost:8080/feed');
+      Object.entries(lastFeedQuery).forEach(([k, v]) => feedURL.searchParams.set(k, v));
+      feedURL.searchParams.set('cursor', nextCursor);
+      const res = await fetch(feedURL.toString(), { credentials: 'include' });
+      const data = await res.json();
+
+      const sub = subQuery.trim().toLowerCase();
+      const more = (data.items || []).filter(item =>
+        !sub ||
+        item.title?.toLowerCase().includes(sub) ||
+        item.description?.toLowerCase().includes(sub)
+      );
+      setFeedItems(prev => {
+        const seen = new Set(prev.map(item => item.link));
+        return [...prev, ...more.filter(item => !seen.has(item.link))];
+      });
+      setNextCursor(data.next_cursor || null);
+    } catch (err) {
+      console.error("Failed to load more results:", err);
+    } finally {
+      setIsLoadingMore(false);
+    }
+  };
   
   const handleLogout = async () => {
     await fetch('http://localhost:8080/logout', { method: 'POST', credentials: 'include' });
@@ -94,11 +135,13 @@ export default function Feed() {
     try {
       const url = new URL('http://localhost:8080/feed');
       url.searchParams.set('query', ''); // or "all"
-      url.searchParams.set('filter', 'save');
+      url.searchParams.set('reaction', 'save');
+      url.searchParams.set('sort', 'date');
+      url.searchParams.set('limit', '200');
   
       const res = await fetch(url.toString(), { credentials: 'include' });
       const data = await res.json();
-      setSavedItems(Array.isArray(data) ? data : []);
+      setSavedItems(Array.isArray(data.items) ? data.items : []);
       setHasLoadedSaved(true);
     } catch (err) {
       console.error("Failed to fetch saved articles:", err);
@@ -107,6 +150,34 @@ export default function Feed() {
     }
   };
     
+  // 🏠 "For You": latest articles ranked by your topics, recency and source variety
+  const loadForYou = async () => {
+    if (isLoading) return;
+
+    setIsLoading(true);
+    setHasSearched(true);
+    setHasContinued(false);
+    setShowContinueOptions(false);
+
+    try {
+      const feedParams = { limit: '50' };
+      const feedURL = new URL('http://localhost:8080/feed');
+      Object.entries(feedParams).forEach(([k, v]) => feedURL.searchParams.set(k, v));
+      const res = await fetch(feedURL.toString(), { credentials: 'include' });
+      const data = await res.json();
+      setLastFeedQuery(feedParams);
+      setNextCursor(data.next_cursor || null);
+      setDidYouMean(null);
+      setFeedItems(Array.isArray(data.items) ? data.items : []);
+      setLastQuery('');
+      setIsForYou(true);
+    } catch (err) {
+      console.error("Failed to load For You feed:", err);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
   const handleSearch = async (customQuery = "", filterOverride = null) => {
     if (isLoading) return;
   
@@ -117,21 +188,20 @@ export default function Feed() {
     setHasSearched(true);
     setHasContinued(false);
     setShowContinueOptions(false);
+    setIsForYou(false);
   
     try {
-      const relatedRes = await fetch(`https://api.datamuse.com/words?ml=${encodeURIComponent(actualQuery)}&max=5`);
-      const relatedData = await relatedRes.json();
-      const relatedTerms = relatedData.map(item => item.word.toLowerCase());
-      const allQueries = [actualQuery.toLowerCase(), ...relatedTerms];
-  
-      setRandomTopics(relatedTerms.slice(0, 3));
-  
-      // 📰 Fetch from /feed
+      // 📰 Fetch from /feed (acronyms/synonyms are expanded server-side; related keywords when opted in)
+      const feedParams = { query: actualQuery, sort: 'date', limit: '50' };
+      if (expand) feedParams.expand = 'true';
+      if (currentFilter !== "all") feedParams.reaction = currentFilter;
       const feedURL = new URL('http://localhost:8080/feed');
-      feedURL.searchParams.set("query", allQueries.join(','));
-      if (currentFilter !== "all") feedURL.searchParams.set("filter", currentFilter);
+      Object.entries(feedParams).forEach(([k, v]) => feedURL.searchParams.set(k, v));
       const feedRes = await fetch(feedURL.toString(), { credentials: 'include' });
       const feedData = await feedRes.json();
+      setLastFeedQuery(feedParams);
+      setNextCursor(feedData.next_cursor || null);
+      setDidYouMean(feedData.did_you_mean || null);
   
       // 🏛 Fetch from /federal (just use main query to avoid 500s)
       let federalData = [];
@@ -142,7 +212,7 @@ export default function Feed() {
         federalData = await federalRes.json();
       }
   
-      let combinedItems = [...feedData, ...federalData];
+      let combinedItems = [...(feedData.items || []), ...federalData];
   
       // 🔍 Subquery refinement (local filtering)
       const sub = subQuery.trim().toLowerCase();
@@ -494,6 +564,16 @@ export default function Feed() {
             fontSize: '1rem'
             }}
         />
+
+        <label style={{ color: '#bbb', fontSize: '0.9rem', whiteSpace: 'nowrap', cursor: 'pointer' }}>
+          <input
+            type="checkbox"
+            checked={expand}
+            onChange={e => setExpand(e.target.checked)}
+            style={{ marginRight: 6, accentColor: '#7c3aed' }}
+          />
+          Related keywords
+        </label>
         
         <button
         onClick={() => handleSearch()}
@@ -521,6 +601,20 @@ export default function Feed() {
         </button>
         </div>
 
+        {!isLoading && didYouMean && activeTab === 'feed' && (
+          <p style={{ color: '#bbb', marginBottom: 20 }}>
+            Did you mean{' '}
+            <a
+              href="#"
+              onClick={e => { e.preventDefault(); handleSearch(didYouMean); }}
+              style={{ color: '#7c3aed' }}
+            >
+              {didYouMean}
+            </a>
+            ?
+          </p>
+        )}
+
         <div>
         {isLoading ? (
             <LoadingBadge />
@@ -528,7 +622,7 @@ export default function Feed() {
             
             
         [...filteredFeedItems]
-        .sort((a, b) => new Date(b.published) - new Date(a.published)) // Newest first
+        .sort((a, b) => isForYou ? 0 : new Date(b.published) - new Date(a.published)) // Newest first
         .slice(0, visibleCount)
         .map((item, idx) => {
         const imgSrc = extractImageSrc(item.description);
@@ -560,8 +654,23 @@ export default function Feed() {
                 </span>
                 )}
             </div>
-            <h3 style={{ fontSize: '1.1rem', marginBottom: 8 }}>{item.title}</h3>
-            <p style={{ color: '#bbb' }}>{excerpt}</p>
+            {item.highlights ? (
+              <>
+                <h3
+                  style={{ fontSize: '1.1rem', marginBottom: 8 }}
+                  dangerouslySetInnerHTML={{ __html: DOMPurify.sanitize(item.highlights.title, { ALLOWED_TAGS: ['mark'] }) }}
+                />
+                <p
+                  style={{ color: '#bbb' }}
+                  dangerouslySetInnerHTML={{ __html: DOMPurify.sanitize(item.highlights.snippet || excerpt, { ALLOWED_TAGS: ['mark'] }) }}
+                />
+              </>
+            ) : (
+              <>
+                <h3 style={{ fontSize: '1.1rem', marginBottom: 8 }}>{item.title}</h3>
+                <p style={{ color: '#bbb' }}>{excerpt}</p>
+              </>
+            )}
             <p style={{ fontSize: '0.8rem', color: '#666', marginTop: 6 }}>
                 {new Date(item.published).toLocaleDateString()}
             </p>
@@ -625,16 +734,14 @@ export default function Feed() {
     </div>
     )}
 
-        {!isLoading && hasSearched && query === lastQuery && filteredFeedItems.length === 0 && (
+        {!isLoading && hasSearched && !isForYou && query === lastQuery && filteredFeedItems.length === 0 && (
           <p style={{ fontStyle: 'italic', color: '#555', marginTop: 20 }}>No results found for "{query}". Try a different topic below.</p>
         )}
 
         {!feedGenerated && !hasSearched && (
           <button
             onClick={() => {
-              const top5Topics = boostedTopics.slice(0, 5);
-              const randomTopic = top5Topics[Math.floor(Math.random() * top5Topics.length)];
-              handleSearch(randomTopic);
+              loadForYou();
               setFeedGenerated(true);
             }}
             style={{
@@ -703,6 +810,7 @@ export default function Feed() {
             isOpen={summaryModalOpen}
             onClose={() => setSummaryModalOpen(false)}
             content={currentSummary}
+            link={currentArticleLink}
             />
         </div>
       </div>
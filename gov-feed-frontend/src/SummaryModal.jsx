@@ -1,7 +1,23 @@
-import React from 'react';
+import React, { useEffect, useState } from 'react';
 import './App.css';
 
-export default function SummaryModal({ isOpen, onClose, content }) {
+export default function SummaryModal({ isOpen, onClose, content, link }) {
+  const [related, setRelated] = useState([]);
+
+  // 🔗 "Related coverage" for the article being summarized
+  useEffect(() => {
+    if (!isOpen || !link) return;
+    let cancelled = false;
+    setRelated([]);
+    fetch(`http://localhost:8080/related?link=${encodeURIComponent(link)}&limit=5`, {
+      credentials: 'include',
+    })
+      .then(res => (res.ok ? res.json() : { items: [] }))
+      .then(data => { if (!cancelled) setRelated(data.items || []); })
+      .catch(() => { if (!cancelled) setRelated([]); });
+    return () => { cancelled = true; };
+  }, [isOpen, link]);
+
   if (!isOpen) return null;
 
   // Split the content by bullet points
@@ -54,6 +70,22 @@ export default function SummaryModal({ isOpen, onClose, content }) {
         ) : (
           <p style={{ fontStyle: 'italic', color: '#ccc' }}>{content}</p>
         )}
+
+        {related.length > 0 && (
+          <div style={{ marginTop: '20px', borderTop: '1px solid #333', paddingTop: '12px' }}>
+            <h3 style={{ fontSize: '1rem', marginBottom: '8px', color: '#c4b5fd' }}>Related coverage</h3>
+            <ul style={{ paddingLeft: '1.25rem' }}>
+              {related.map(item => (
+                <li key={item.link} style={{ marginBottom: '0.4rem' }}>
+                  <a href={item.link} target="_blank" rel="noopener noreferrer" style={{ color: '#fff' }}>
+                    {item.title}
+                  </a>
+                  <span style={{ color: '#888', fontSize: '0.85rem' }}> — {item.source}</span>
+                </li>
+              ))}
+            </ul>
+          </div>
+        )}
       </div>
     </div>
   );